@@ -1,10 +1,17 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fezcode/atlas.compass/internal/cli"
+	"github.com/fezcode/atlas.compass/internal/clipboard"
+	"github.com/fezcode/atlas.compass/internal/config"
 	"github.com/fezcode/atlas.compass/internal/tui"
 )
 
@@ -16,9 +23,46 @@ func main() {
 		return
 	}
 
-	p := tea.NewProgram(tui.NewMainModel(), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+	// Invoked by docker through a docker-credential-compass link
+	if strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe") == cli.DockerHelperName {
+		g, _, err := cli.ParseGlobals(nil)
+		if err != nil {
+			os.Exit(cli.ExitUsage) // Already reported, e.g. a bad ATLAS_COMPASS_VAULT
+		}
+		os.Exit(cli.Run(g, append([]string{"docker-credential"}, os.Args[1:]...)))
+	}
+
+	g, args, err := cli.ParseGlobals(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		os.Exit(cli.ExitUsage)
+	}
+	if len(args) > 0 {
+		os.Exit(cli.Run(g, args))
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	cb, err := clipboard.New(cfg.Clipboard)
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	p := tea.NewProgram(tui.NewMainModel(cfg, tui.Options{Keyfile: g.Keyfile, Clipboard: cb, NoAgent: g.NoAgent}), tea.WithAltScreen())
+	final, err := p.Run()
+	if m, ok := final.(tui.MainModel); ok {
+		m.Clipboard.Clear()
+		m.Session.Close()
+	}
+	if err != nil {
 		fmt.Printf("Error running atlas.compass: %v\n", err)
 		os.Exit(1)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,171 @@
+// Package agent keeps unlocked vault keys in a background process, so
+// commands run shortly after one another do not each prompt for the master
+// password and pay for key derivation. It works like ssh-agent: keys are
+// served over a Unix socket that only the same user may connect to.
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"time"
+
+	"github.com/fezcode/atlas.compass/internal/crypto"
+	"github.com/fezcode/atlas.compass/internal/store"
+	"github.com/fezcode/atlas.compass/pkg/model"
+)
+
+// SocketName is the agent socket file in the ~/.atlas directory.
+const SocketName = "agent.sock"
+
+// DefaultTTL is how long the agent keeps a key when no TTL is given.
+const DefaultTTL = 15 * time.Minute
+
+var (
+	// ErrNotRunning is returned by the client functions when no agent
+	// listens on the socket.
+	ErrNotRunning = errors.New("agent is not running")
+
+	// ErrLocked is returned by Get when the agent holds no key for the
+	// vault, because it was never added, expired or was locked.
+	ErrLocked = errors.New("agent holds no key for this vault")
+)
+
+// Held describes a key held by the agent.
+type Held struct {
+	Vault   string    `json:"vault"`
+	Expires time.Time `json:"expires"` // Zero if the key does not expire
+}
+
+// request is one call to the agent. Each connection carries one request
+// and its response, as single lines of JSON.
+type request struct {
+	Op    string `json:"op"`              // add, get, lock, status or stop
+	Vault string `json:"vault,omitempty"` // For lock, limits it to this vault
+	Key   []byte `json:"key,omitempty"`
+	Slot  int    `json:"slot,omitempty"`
+}
+
+type response struct {
+	Err    string `json:"err,omitempty"`
+	Locked bool   `json:"locked,omitempty"`
+	Key    []byte `json:"key,omitempty"`
+	Slot   int    `json:"slot,omitempty"`
+	Held   []Held `json:"held,omitempty"`
+}
+
+// SocketPath returns the path of the agent socket.
+func SocketPath() (string, error) {
+	dir, err := store.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, SocketName), nil
+}
+
+// Add hands the data key of the vault file at path vault to the agent,
+// with the index of the key slot that unwrapped it.
+func Add(vault string, key []byte, slot int) error {
+	_, err := call(request{Op: "add", Vault: vault, Key: key, Slot: slot})
+	return err
+}
+
+// Get returns the data key the agent holds for the vault file at path vault
+// and its slot index. The caller must wipe the key.
+func Get(vault string) ([]byte, int, error) {
+	resp, err := call(request{Op: "get", Vault: vault})
+	if err != nil {
+		return nil, 0, err
+	}
+	return resp.Key, resp.Slot, nil
+}
+
+// Lock makes the agent forget every key it holds.
+func Lock() error {
+	_, err := call(request{Op: "lock"})
+	return err
+}
+
+// LockVault makes the agent forget the key it holds for the vault file at
+// path vault, keeping those of other vaults.
+func LockVault(vault string) error {
+	_, err := call(request{Op: "lock", Vault: vault})
+	return err
+}
+
+// Status lists the keys the agent holds.
+func Status() ([]Held, error) {
+	resp, err := call(request{Op: "status"})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Held, nil
+}
+
+// Stop makes the agent forget its keys and exit.
+func Stop() error {
+	_, err := call(request{Op: "stop"})
+	return err
+}
+
+// Open unlocks the vault with the key the agent holds for it, in the given
+// mode. The caller must Close the session.
+func Open(mode store.Mode) (*model.Vault, *store.Session, error) {
+	path, err := store.GetVaultPath()
+	if err != nil {
+		return nil, nil, err
+	}
+	key, slot, err := Get(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer crypto.Wipe(key)
+	return store.LoadKey(key, slot, mode)
+}
+
+// AddSession hands the key of an unlocked session to the agent. Sessions
+// of vaults not yet written in the current format are skipped.
+func AddSession(s *store.Session) error {
+	key := s.Key()
+	if key == nil {
+		return nil
+	}
+	return Add(s.Path(), key, s.Slot())
+}
+
+func call(req request) (*response, error) {
+	path, err := SocketPath()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return nil, ErrNotRunning
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	msg, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Write(append(msg, '\n'))
+	crypto.Wipe(msg)
+	if err != nil {
+		return nil, fmt.Errorf("agent: %w", err)
+	}
+
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("agent: %w", err)
+	}
+	switch {
+	case resp.Locked:
+		return nil, ErrLocked
+	case resp.Err != "":
+		return nil, fmt.Errorf("agent: %s", resp.Err)
+	}
+	return &resp, nil
+}
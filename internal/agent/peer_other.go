@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !freebsd
+
+package agent
+
+import (
+	"errors"
+	"net"
+)
+
+// The agent needs the peer's credentials to trust a connection, so it
+// refuses to run where they cannot be read.
+const peerCredSupported = false
+
+func peerUID(*net.UnixConn) (int, error) {
+	return -1, errors.New("peer credentials are not supported on this platform")
+}
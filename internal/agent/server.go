@@ -0,0 +1,239 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fezcode/atlas.compass/internal/crypto"
+)
+
+// Server holds vault keys and serves them on the agent socket.
+type Server struct {
+	ttl time.Duration
+
+	mu       sync.Mutex
+	keys     map[string]*heldKey
+	listener net.Listener
+	path     string
+}
+
+type heldKey struct {
+	key     *crypto.SecretBuffer
+	slot    int
+	expires time.Time
+	timer   *time.Timer
+}
+
+// NewServer returns a server that forgets each key ttl after it was added.
+// A ttl of zero keeps keys until Lock or Stop.
+func NewServer(ttl time.Duration) *Server {
+	return &Server{ttl: ttl, keys: map[string]*heldKey{}}
+}
+
+// Listen creates the agent socket, readable by the current user only. It
+// fails if another agent is already listening on it.
+func (s *Server) Listen() error {
+	if !peerCredSupported {
+		return errors.New("the agent is not supported on this platform")
+	}
+	path, err := SocketPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("an agent is already running on %s", path)
+	}
+	os.Remove(path) // Left behind by an agent that did not shut down cleanly
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		l.Close()
+		return err
+	}
+	s.listener, s.path = l, path
+	return nil
+}
+
+// Path returns the socket path once Listen has succeeded.
+func (s *Server) Path() string {
+	return s.path
+}
+
+// Serve answers requests until Stop is called or a stop request arrives.
+func (s *Server) Serve() error {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handle(conn.(*net.UnixConn))
+	}
+}
+
+// Stop wipes every key, closes the socket and removes it.
+func (s *Server) Stop() {
+	s.lock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener != nil {
+		s.listener.Close()
+		os.Remove(s.path)
+		s.listener = nil
+	}
+}
+
+func (s *Server) handle(conn *net.UnixConn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// The socket permissions already keep other users out; checking the
+	// peer guards against a socket made reachable by accident.
+	uid, err := peerUID(conn)
+	if err != nil || uid != os.Getuid() {
+		return
+	}
+
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return
+	}
+	var req request
+	err = json.Unmarshal(line, &req)
+	crypto.Wipe(line)
+	if err != nil {
+		writeResponse(conn, response{Err: "malformed request"})
+		return
+	}
+	defer crypto.Wipe(req.Key)
+
+	resp := s.do(req)
+	writeResponse(conn, resp)
+	crypto.Wipe(resp.Key)
+
+	if req.Op == "stop" {
+		s.Stop()
+	}
+}
+
+func (s *Server) do(req request) response {
+	switch req.Op {
+	case "add":
+		if req.Vault == "" || len(req.Key) == 0 {
+			return response{Err: "add needs a vault and a key"}
+		}
+		if err := s.add(req.Vault, req.Key, req.Slot); err != nil {
+			return response{Err: err.Error()}
+		}
+		return response{}
+	case "get":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		h, ok := s.keys[req.Vault]
+		if !ok {
+			return response{Locked: true}
+		}
+		return response{Key: append([]byte(nil), h.key.Bytes()...), Slot: h.slot}
+	case "lock":
+		if req.Vault != "" {
+			s.lockVault(req.Vault)
+		} else {
+			s.lock()
+		}
+		return response{}
+	case "stop":
+		s.lock()
+		return response{}
+	case "status":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		held := make([]Held, 0, len(s.keys))
+		for vault, h := range s.keys {
+			held = append(held, Held{Vault: vault, Expires: h.expires})
+		}
+		return response{Held: held}
+	}
+	return response{Err: fmt.Sprintf("unknown request %q", req.Op)}
+}
+
+func (s *Server) add(vault string, key []byte, slot int) error {
+	buf, err := crypto.SecretBufferFrom(append([]byte(nil), key...))
+	if err != nil {
+		return err
+	}
+	h := &heldKey{key: buf, slot: slot}
+	if s.ttl > 0 {
+		h.expires = time.Now().Add(s.ttl)
+		h.timer = time.AfterFunc(s.ttl, func() { s.forget(vault, h) })
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.keys[vault]; ok {
+		old.destroy()
+	}
+	s.keys[vault] = h
+	return nil
+}
+
+// forget drops the key for vault if it is still h.
+func (s *Server) forget(vault string, h *heldKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys[vault] == h {
+		h.destroy()
+		delete(s.keys, vault)
+	}
+}
+
+func (s *Server) lock() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for vault, h := range s.keys {
+		h.destroy()
+		delete(s.keys, vault)
+	}
+}
+
+// lockVault drops the key for vault, if there is one.
+func (s *Server) lockVault(vault string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if h, ok := s.keys[vault]; ok {
+		h.destroy()
+		delete(s.keys, vault)
+	}
+}
+
+func (h *heldKey) destroy() {
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+	h.key.Destroy()
+}
+
+func writeResponse(conn net.Conn, resp response) {
+	msg, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	conn.Write(append(msg, '\n'))
+	crypto.Wipe(msg)
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/fezcode/atlas.compass/internal/agent"
+)
+
+func runAgent(_ Globals, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "status":
+			return agentStatus()
+		case "lock":
+			if err := agent.Lock(); err != nil {
+				return err
+			}
+			fmt.Println("Agent locked.")
+			return nil
+		case "stop":
+			if err := agent.Stop(); err != nil {
+				return err
+			}
+			fmt.Println("Agent stopped.")
+			return nil
+		}
+	}
+
+	fs := flag.NewFlagSet("agent", flag.ContinueOnError)
+	ttl := fs.Duration("ttl", agent.DefaultTTL, "forget each key this long after it was added (0 keeps it until locked)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errors.New("usage: atlas.compass agent [-ttl 15m] | agent <status|lock|stop>")
+	}
+
+	s := agent.NewServer(*ttl)
+	if err := s.Listen(); err != nil {
+		return err
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		s.Stop()
+	}()
+
+	fmt.Fprintf(os.Stderr, "Agent listening on %s. The next unlock hands it the vault key.\n", s.Path())
+	return s.Serve()
+}
+
+func agentStatus() error {
+	held, err := agent.Status()
+	if err != nil {
+		return err
+	}
+	if len(held) == 0 {
+		fmt.Println("Agent running, locked.")
+		return nil
+	}
+	fmt.Println("Agent running, holding keys for:")
+	for _, h := range held {
+		if h.Expires.IsZero() {
+			fmt.Printf("  %s (until locked)\n", h.Vault)
+		} else {
+			fmt.Printf("  %s (%s left)\n", h.Vault, time.Until(h.Expires).Round(time.Second))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/fezcode/atlas.compass/internal/config"
+	"github.com/fezcode/atlas.compass/internal/crypto"
+	"github.com/fezcode/atlas.compass/internal/store"
+)
+
+func runCalibrate(_ Globals, args []string) error {
+	fs := flag.NewFlagSet("calibrate", flag.ContinueOnError)
+	target := fs.Duration("target", time.Second, "desired unlock latency")
+	maxMem := fs.Uint("max-memory", 1024, "upper bound for memory cost in MiB")
+	threads := fs.Uint("threads", crypto.ArgonThreads, "Argon2id parallelism")
+	save := fs.Bool("save", false, "store the result as the configured minimum")
+	thisVault := fs.Bool("this-vault", false, "store the result for the selected vault only (with -save)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *threads == 0 || *threads > 255 {
+		return fmt.Errorf("threads must be between 1 and 255")
+	}
+	if *maxMem == 0 || *maxMem > crypto.MaxArgonMem/1024 {
+		return fmt.Errorf("max-memory must be between 1 and %d MiB", crypto.MaxArgonMem/1024)
+	}
+
+	fmt.Printf("Calibrating Argon2id for a %s unlock...\n", *target)
+	params, elapsed := crypto.Calibrate(*target, uint32(*maxMem)*1024, uint8(*threads))
+	fmt.Printf("Selected %s (measured %s)\n", params, elapsed.Round(time.Millisecond))
+
+	if !*save {
+		fmt.Println("Run again with -save to use these parameters.")
+		return nil
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if *thisVault {
+		v := cfg.VaultConfig(store.Name())
+		v.KDF = config.FromParams(params)
+		cfg.SetVaultConfig(store.Name(), v)
+	} else {
+		cfg.KDF = config.FromParams(params)
+	}
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+
+	path, _ := config.Path()
+	fmt.Printf("Saved to %s. Vaults below this cost are re-encrypted on next unlock.\n", path)
+	return nil
+}
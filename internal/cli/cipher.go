@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fezcode/atlas.compass/internal/config"
+	"github.com/fezcode/atlas.compass/internal/crypto"
+	"github.com/fezcode/atlas.compass/internal/store"
+)
+
+// runCipher shows the payload cipher of the vault, or switches it and
+// records the choice in the config.
+func runCipher(g Globals, args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage: atlas.compass cipher [name]")
+	}
+
+	if len(args) == 0 {
+		if !store.Exists() {
+			fmt.Printf("No vault yet. New vaults use %s.\n", crypto.DefaultCipher)
+		} else {
+			c, err := store.Cipher()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Vault cipher: %s\n", c)
+		}
+		fmt.Print("Available:")
+		for _, c := range crypto.Ciphers {
+			fmt.Printf(" %s", c)
+		}
+		fmt.Println()
+		return nil
+	}
+
+	c, err := crypto.CipherByName(args[0])
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	// Record the choice first, so unlocking re-encrypts with c rather than
+	// with the cipher configured before
+	v := cfg.VaultConfig(store.Name())
+	v.Cipher = c.String()
+	cfg.SetVaultConfig(store.Name(), v)
+
+	if store.Exists() {
+		_, session, err := unlockVault(g, cfg, newPrompter(), store.ReadWrite)
+		if err != nil {
+			return err
+		}
+		// Sessions from the agent are not upgraded on unlock
+		if have, cerr := store.Cipher(); cerr != nil || have.ID() != c.ID() {
+			err = session.SetCipher(c)
+		}
+		session.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Vault cipher set to %s.\n", c)
+	return nil
+}
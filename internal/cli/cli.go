@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/fezcode/atlas.compass/internal/store"
+	"github.com/fezcode/atlas.compass/pkg/model"
+)
+
+// Globals are flags given before the command. They apply to every command
+// and to the terminal UI.
+type Globals struct {
+	Vault    string
+	Keyfile  string
+	Password PasswordSource
+	NoAgent  bool
+}
+
+// command is a non-interactive subcommand.
+type command struct {
+	summary string
+	run     func(g Globals, args []string) error
+}
+
+var commands = map[string]command{
+	"add":               {"Add an entry", runAdd},
+	"agent":             {"Keep the vault unlocked in the background, or status/lock/stop it", runAgent},
+	"calibrate":         {"Benchmark Argon2id and pick key derivation cost", runCalibrate},
+	"cipher":            {"Show or change the cipher that encrypts the vault", runCipher},
+	"docker-credential": {"docker credential helper: get, store, erase or list registry logins", runDockerCredential},
+	"edit":              {"Change fields of an entry", runEdit},
+	"get":               {"Print an entry, by title or ID", runGet},
+	"git-credential":    {"git credential helper: get, store or erase HTTPS credentials", runGitCredential},
+	"inject":            {"Render a template, filling in {{ compass \"<title|id>\" \"<field>\" }} from the vault", runInject},
+	"keyfile":           {"Generate a keyfile, or enable/disable it as a second factor", runKeyfile},
+	"list":              {"List entries", runList},
+	"passwd":            {"Change the master password", runPasswd},
+	"recovery":          {"Split a recovery key into shares, or combine shares to unlock", runRecovery},
+	"run":               {"Run a command with vault fields in its environment", runRun},
+	"rm":                {"Delete an entry", runRemove},
+	"vaults":            {"List the named vaults in ~/.atlas", runVaults},
+}
+
+// VaultEnv names the vault to use when -vault is not given.
+const VaultEnv = "ATLAS_COMPASS_VAULT"
+
+// ParseGlobals parses the global flags at the start of args and returns them
+// with the remaining arguments (the command and its own flags). It also
+// selects the vault they name in the store package.
+func ParseGlobals(args []string) (Globals, []string, error) {
+	var g Globals
+	fs := flag.NewFlagSet("atlas.compass", flag.ContinueOnError)
+	fs.StringVar(&g.Vault, "vault", os.Getenv(VaultEnv), "vault name in ~/.atlas, or path of a vault file (default $"+VaultEnv+", else the default vault)")
+	fs.StringVar(&g.Keyfile, "keyfile", "", "keyfile to combine with the master password")
+	fs.BoolVar(&g.Password.Stdin, "password-stdin", false, "read the master password from the first line of stdin")
+	fs.IntVar(&g.Password.FD, "password-fd", -1, "read the master password from this file descriptor")
+	fs.StringVar(&g.Password.File, "password-file", "", "read the master password from the first line of this file")
+	fs.StringVar(&g.Password.Command, "password-command", "", "run this shell command and use the first line it prints as the master password")
+	fs.BoolVar(&g.Password.Env, "password-env", false, "read the master password from $"+PasswordEnv+" (insecure)")
+	fs.BoolVar(&g.NoAgent, "no-agent", false, "neither use nor feed a running agent")
+	fs.Usage = func() {
+		usage(fs.Output())
+		fmt.Fprintln(fs.Output(), "\nGlobal flags:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return g, nil, err
+	}
+	if err := store.Select(g.Vault); err != nil {
+		fmt.Fprintf(fs.Output(), "invalid value %q for -vault: %v\n", g.Vault, err)
+		return g, nil, err
+	}
+	return g, fs.Args(), nil
+}
+
+// Exit codes returned by Run.
+const (
+	ExitOK        = 0
+	ExitError     = 1
+	ExitUsage     = 2
+	ExitNotFound  = 3 // No entry matches the given title or ID
+	ExitAmbiguous = 4 // The title matches several entries
+)
+
+// exitCode is returned by commands that pass on the exit status of a child
+// process. Run exits with it without printing an error.
+type exitCode int
+
+func (c exitCode) Error() string {
+	return fmt.Sprintf("exit status %d", int(c))
+}
+
+// Run executes the subcommand named by args[0] and returns the process exit code.
+func Run(g Globals, args []string) int {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return ExitUsage
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+		usage(os.Stderr)
+		return ExitUsage
+	}
+
+	if err := cmd.run(g, args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		var code exitCode
+		if errors.As(err, &code) && code > 0 {
+			return int(code)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if hint := store.Hint(err); hint != "" {
+			fmt.Fprintf(os.Stderr, "Hint: %s\n", hint)
+		}
+		switch {
+		case errors.Is(err, model.ErrEntryNotFound):
+			return ExitNotFound
+		case errors.Is(err, model.ErrAmbiguous):
+			return ExitAmbiguous
+		}
+		return ExitError
+	}
+	return ExitOK
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: atlas.compass [global flags] [command] [flags]")
+	fmt.Fprintln(w, "\nRun without a command to open the vault in the terminal UI.")
+	fmt.Fprintln(w, "\nCommands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-18s %s\n", name, commands[name].summary)
+	}
+}
@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fezcode/atlas.compass/internal/store"
+	"github.com/fezcode/atlas.compass/pkg/model"
+)
+
+// DockerTag marks the entries used by the docker credential helper.
+const DockerTag = "docker"
+
+// DockerHelperName is the executable name docker looks for when the config
+// says "credsStore": "compass". Link it to atlas.compass.
+const DockerHelperName = "docker-credential-compass"
+
+// errDockerNotFound is the message docker recognises as "no credentials".
+var errDockerNotFound = errors.New("credentials not found in native keychain")
+
+// dockerCredentials is the JSON document of the docker credential helper
+// protocol.
+type dockerCredentials struct {
+	ServerURL string
+	Username  string
+	Secret    string
+}
+
+// runDockerCredential implements the docker credential helper protocol
+// with entries tagged DockerTag. As the protocol requires, errors are also
+// written to stdout, where docker reads them.
+func runDockerCredential(g Globals, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: atlas.compass docker-credential <get|store|erase|list>")
+	}
+
+	var err error
+	switch args[0] {
+	case "get":
+		err = dockerCredentialGet(g, os.Stdin, os.Stdout)
+	case "store":
+		err = dockerCredentialStore(g, os.Stdin)
+	case "erase":
+		err = dockerCredentialErase(g, os.Stdin)
+	case "list":
+		err = dockerCredentialList(g, os.Stdout)
+	default:
+		err = fmt.Errorf("unknown docker-credential action %q", args[0])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stdout, err)
+	}
+	return err
+}
+
+func dockerCredentialGet(g Globals, r io.Reader, w io.Writer) error {
+	server, err := readServerURL(r)
+	if err != nil {
+		return err
+	}
+	_, vault, session, err := openVault(g, newTTYPrompter(), store.ReadOnly)
+	if err != nil {
+		return err
+	}
+	session.Close()
+
+	i := findDockerEntry(vault, server)
+	if i < 0 {
+		return errDockerNotFound
+	}
+	e := vault.Entries[i]
+	return json.NewEncoder(w).Encode(dockerCredentials{ServerURL: server, Username: e.Username, Secret: e.Password})
+}
+
+func dockerCredentialStore(g Globals, r io.Reader) error {
+	var creds dockerCredentials
+	if err := json.NewDecoder(r).Decode(&creds); err != nil {
+		return fmt.Errorf("invalid credentials: %w", err)
+	}
+	if creds.ServerURL == "" {
+		return errors.New("missing server URL")
+	}
+	_, vault, session, err := openVault(g, newTTYPrompter(), store.ReadWrite)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	now := time.Now()
+	if i := findDockerEntry(vault, creds.ServerURL); i >= 0 {
+		e := &vault.Entries[i]
+		if e.Username == creds.Username && e.Password == creds.Secret {
+			return nil
+		}
+		e.Username, e.Password, e.UpdatedAt = creds.Username, creds.Secret, now
+		return session.Save(vault)
+	}
+
+	vault.Entries = append(vault.Entries, model.Entry{
+		ID:        model.NewID(),
+		Title:     "Docker: " + normalizeServerURL(creds.ServerURL),
+		Username:  creds.Username,
+		Password:  creds.Secret,
+		URL:       creds.ServerURL,
+		Tags:      []string{DockerTag},
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	return session.Save(vault)
+}
+
+func dockerCredentialErase(g Globals, r io.Reader) error {
+	server, err := readServerURL(r)
+	if err != nil {
+		return err
+	}
+	_, vault, session, err := openVault(g, newTTYPrompter(), store.ReadWrite)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	i := findDockerEntry(vault, server)
+	if i < 0 {
+		return errDockerNotFound
+	}
+	vault.Remove(i)
+	return session.Save(vault)
+}
+
+func dockerCredentialList(g Globals, w io.Writer) error {
+	_, vault, session, err := openVault(g, newTTYPrompter(), store.ReadOnly)
+	if err != nil {
+		return err
+	}
+	session.Close()
+
+	servers := map[string]string{}
+	for _, e := range vault.Entries {
+		if e.HasTag(DockerTag) && e.URL != "" {
+			servers[e.URL] = e.Username
+		}
+	}
+	return json.NewEncoder(w).Encode(servers)
+}
+
+func readServerURL(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	server := strings.TrimSpace(string(data))
+	if server == "" {
+		return "", errors.New("missing server URL")
+	}
+	return server, nil
+}
+
+// findDockerEntry returns the index of the most recently updated entry
+// tagged DockerTag whose URL names the same registry as server, or -1.
+func findDockerEntry(vault *model.Vault, server string) int {
+	want := normalizeServerURL(server)
+	best := -1
+	for i, e := range vault.Entries {
+		if !e.HasTag(DockerTag) || normalizeServerURL(e.URL) != want {
+			continue
+		}
+		if best < 0 || e.UpdatedAt.After(vault.Entries[best].UpdatedAt) {
+			best = i
+		}
+	}
+	return best
+}
+
+// normalizeServerURL reduces a registry address to host and path, so
+// "https://ghcr.io/" and "ghcr.io" compare equal.
+func normalizeServerURL(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "://"); i >= 0 {
+		s = s[i+3:]
+	}
+	s = strings.TrimRight(s, "/")
+	host, path, _ := strings.Cut(s, "/")
+	if path != "" {
+		return strings.ToLower(host) + "/" + path
+	}
+	return strings.ToLower(host)
+}
@@ -0,0 +1,419 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fezcode/atlas.compass/internal/config"
+	"github.com/fezcode/atlas.compass/internal/crypto"
+	"github.com/fezcode/atlas.compass/internal/store"
+	"github.com/fezcode/atlas.compass/pkg/model"
+)
+
+// entryFlags are the entry fields that add and edit accept as flags.
+type entryFlags struct {
+	title, username, url, notes, tags string
+}
+
+func (f *entryFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.title, "title", "", "entry title")
+	fs.StringVar(&f.username, "username", "", "username")
+	fs.StringVar(&f.url, "url", "", "URL")
+	fs.StringVar(&f.notes, "notes", "", "notes")
+	fs.StringVar(&f.tags, "tags", "", "comma-separated tags")
+}
+
+// entryFields are the names accepted by -field, the same as the JSON keys.
+var entryFields = []string{"id", "title", "username", "password", "url", "notes", "tags", "created_at", "updated_at"}
+
+// entryField returns the named field of e as text.
+func entryField(e model.Entry, name string) (string, error) {
+	switch strings.ToLower(name) {
+	case "id":
+		return e.ID, nil
+	case "title":
+		return e.Title, nil
+	case "username":
+		return e.Username, nil
+	case "password":
+		return e.Password, nil
+	case "url":
+		return e.URL, nil
+	case "notes":
+		return e.Notes, nil
+	case "tags":
+		return strings.Join(e.Tags, ","), nil
+	case "created_at":
+		return e.CreatedAt.Format(time.RFC3339), nil
+	case "updated_at":
+		return e.UpdatedAt.Format(time.RFC3339), nil
+	}
+	return "", fmt.Errorf("unknown field %q (want one of %s)", name, strings.Join(entryFields, ", "))
+}
+
+// splitTags parses a comma-separated tag list, dropping empty tags.
+func splitTags(s string) []string {
+	var tags []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// printJSON writes v to stdout as indented JSON using the model's field
+// names, which are the stable schema for scripts.
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// parseArgs parses flags that may come before or after positional arguments
+// and returns the positional ones.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return rest, nil
+		}
+		rest = append(rest, args[0])
+		args = args[1:]
+	}
+}
+
+// openVault loads the config and unlocks the vault in the given mode. The
+// caller must Close the session.
+func openVault(g Globals, p *prompter, mode store.Mode) (*config.Config, *model.Vault, *store.Session, error) {
+	if !store.Exists() {
+		return nil, nil, nil, store.ErrNotFound
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	vault, session, err := unlockVault(g, cfg, p, mode)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return cfg, vault, session, nil
+}
+
+func runList(g Globals, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the entries as a JSON array")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errors.New("usage: atlas.compass list [-json]")
+	}
+
+	_, vault, session, err := openVault(g, newPrompter(), store.ReadOnly)
+	if err != nil {
+		return err
+	}
+	session.Close()
+
+	entries := append(make([]model.Entry, 0, len(vault.Entries)), vault.Entries...)
+	sort.Slice(entries, func(i, j int) bool {
+		return strings.ToLower(entries[i].Title) < strings.ToLower(entries[j].Title)
+	})
+	if *asJSON {
+		return printJSON(entries)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTITLE\tUSERNAME\tURL")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.ID, e.Title, e.Username, e.URL)
+	}
+	return w.Flush()
+}
+
+func runGet(g Globals, args []string) error {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the entry as a JSON object")
+	field := fs.String("field", "", "print only this field, e.g. password")
+	noNewline := fs.Bool("n", false, "do not print a trailing newline after -field")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return errors.New("usage: atlas.compass get [-json | -field name [-n]] <title|id>")
+	}
+	if *asJSON && *field != "" {
+		return errors.New("-json and -field cannot be combined")
+	}
+	if *field != "" {
+		if _, err := entryField(model.Entry{}, *field); err != nil {
+			return err
+		}
+	}
+
+	_, vault, session, err := openVault(g, newPrompter(), store.ReadOnly)
+	if err != nil {
+		return err
+	}
+	session.Close()
+
+	i, err := vault.Find(rest[0])
+	if err != nil {
+		return err
+	}
+	e := vault.Entries[i]
+
+	switch {
+	case *asJSON:
+		return printJSON(e)
+	case *field != "":
+		value, _ := entryField(e, *field)
+		if !*noNewline {
+			value += "\n"
+		}
+		_, err := io.WriteString(os.Stdout, value)
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%s\n", e.ID)
+	fmt.Fprintf(w, "Title:\t%s\n", e.Title)
+	fmt.Fprintf(w, "Username:\t%s\n", e.Username)
+	fmt.Fprintf(w, "Password:\t%s\n", e.Password)
+	fmt.Fprintf(w, "URL:\t%s\n", e.URL)
+	fmt.Fprintf(w, "Notes:\t%s\n", e.Notes)
+	fmt.Fprintf(w, "Tags:\t%s\n", strings.Join(e.Tags, ", "))
+	fmt.Fprintf(w, "Updated:\t%s\n", e.UpdatedAt.Format(time.RFC3339))
+	return w.Flush()
+}
+
+func runAdd(g Globals, args []string) error {
+	var f entryFlags
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	f.register(fs)
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 1 || (len(rest) == 1 && f.title != "") {
+		return errors.New("usage: atlas.compass add [-username u] [-url u] [-notes n] [-tags a,b] <title>")
+	}
+	if len(rest) == 1 {
+		f.title = rest[0]
+	}
+	if f.title == "" {
+		return errors.New("title cannot be empty")
+	}
+
+	p := newPrompter()
+	_, vault, session, err := openVault(g, p, store.ReadWrite)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	pass, err := p.entryPassword()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	e := model.Entry{
+		ID:        model.NewID(),
+		Title:     f.title,
+		Username:  f.username,
+		Password:  pass,
+		URL:       f.url,
+		Notes:     f.notes,
+		Tags:      splitTags(f.tags),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	vault.Entries = append(vault.Entries, e)
+	if err := session.Save(vault); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Added %q (%s).\n", e.Title, e.ID)
+	return nil
+}
+
+func runEdit(g Globals, args []string) error {
+	var f entryFlags
+	fs := flag.NewFlagSet("edit", flag.ContinueOnError)
+	f.register(fs)
+	newPass := fs.Bool("password", false, "prompt for a new entry password")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return errors.New("usage: atlas.compass edit [-title t] [-username u] [-url u] [-notes n] [-tags a,b] [-password] <title|id>")
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+	if len(set) == 0 {
+		return errors.New("nothing to change: give at least one of -title, -username, -url, -notes, -tags, -password")
+	}
+	if set["title"] && f.title == "" {
+		return errors.New("title cannot be empty")
+	}
+
+	p := newPrompter()
+	_, vault, session, err := openVault(g, p, store.ReadWrite)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	i, err := vault.Find(rest[0])
+	if err != nil {
+		return err
+	}
+	e := &vault.Entries[i]
+
+	if set["title"] {
+		e.Title = f.title
+	}
+	if set["username"] {
+		e.Username = f.username
+	}
+	if set["url"] {
+		e.URL = f.url
+	}
+	if set["notes"] {
+		e.Notes = f.notes
+	}
+	if set["tags"] {
+		e.Tags = splitTags(f.tags)
+	}
+	if *newPass {
+		if e.Password, err = p.entryPassword(); err != nil {
+			return err
+		}
+	}
+	e.UpdatedAt = time.Now()
+
+	if err := session.Save(vault); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Updated %q.\n", e.Title)
+	return nil
+}
+
+func runRemove(g Globals, args []string) error {
+	fs := flag.NewFlagSet("rm", flag.ContinueOnError)
+	force := fs.Bool("f", false, "do not ask for confirmation")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return errors.New("usage: atlas.compass rm [-f] <title|id>")
+	}
+
+	p := newPrompter()
+	if !*force && !p.tty {
+		return errors.New("refusing to delete without confirmation: use -f")
+	}
+
+	_, vault, session, err := openVault(g, p, store.ReadWrite)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	i, err := vault.Find(rest[0])
+	if err != nil {
+		return err
+	}
+	e := vault.Entries[i]
+
+	if !*force {
+		answer, err := p.line(fmt.Sprintf("Delete %q (%s)? [y/N] ", e.Title, e.ID))
+		if err != nil {
+			return err
+		}
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			return errors.New("cancelled")
+		}
+	}
+
+	vault.Remove(i)
+	if err := session.Save(vault); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Deleted %q.\n", e.Title)
+	return nil
+}
+
+// runPasswd changes the master password. The keyfile, if any, stays
+// required.
+func runPasswd(g Globals, args []string) error {
+	fs := flag.NewFlagSet("passwd", flag.ContinueOnError)
+	allowWeak := fs.Bool("allow-weak", false, "accept a password the strength check rejects")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errors.New("usage: atlas.compass passwd [-allow-weak]")
+	}
+	if !store.Exists() {
+		return store.ErrNotFound
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	p := newPrompter()
+	pass, err := g.masterPassword(p, "Current master password: ")
+	if err != nil {
+		return err
+	}
+	current, err := passwordCredential(g, cfg, pass)
+	if err != nil {
+		return err
+	}
+	defer current.Wipe()
+	_, session, err := store.Load(current, store.ReadWrite)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	next, err := p.newPassword("New master password: ")
+	if err != nil {
+		return err
+	}
+	if s := crypto.EstimateStrength(next); s.Weak() && !*allowWeak {
+		return fmt.Errorf("password is %s: make it longer or mix character types (or pass -allow-weak)", s)
+	}
+	nextCred, err := passwordCredential(g, cfg, next)
+	if err != nil {
+		return err
+	}
+	defer nextCred.Wipe()
+
+	if err := session.ChangePassword(current, nextCred, slotParams(cfg, session)); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "Master password changed.")
+	return nil
+}
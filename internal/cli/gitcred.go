@@ -0,0 +1,236 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fezcode/atlas.compass/internal/store"
+	"github.com/fezcode/atlas.compass/pkg/model"
+)
+
+// gitRequest is a credential description in the git credential helper
+// protocol: key=value lines ending with a blank line or EOF.
+type gitRequest struct {
+	Protocol string
+	Host     string
+	Path     string
+	Username string
+	Password string
+}
+
+// runGitCredential implements the git credential helper protocol, so
+// git can fetch HTTPS credentials from the vault:
+//
+//	git config --global credential.helper '!atlas.compass git-credential'
+func runGitCredential(g Globals, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: atlas.compass git-credential <get|store|erase>")
+	}
+	req, err := readGitRequest(os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "get":
+		return gitCredentialGet(g, req, os.Stdout)
+	case "store":
+		return gitCredentialStore(g, req)
+	case "erase":
+		return gitCredentialErase(g, req)
+	}
+	return nil // Unknown actions must be ignored, per gitcredentials(7)
+}
+
+func readGitRequest(r io.Reader) (gitRequest, error) {
+	var req gitRequest
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			break
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return req, fmt.Errorf("invalid credential line %q", line)
+		}
+		switch key {
+		case "protocol":
+			req.Protocol = value
+		case "host":
+			req.Host = value
+		case "path":
+			req.Path = value
+		case "username":
+			req.Username = value
+		case "password":
+			req.Password = value
+		case "url":
+			u, err := url.Parse(value)
+			if err != nil {
+				return req, fmt.Errorf("invalid credential url: %w", err)
+			}
+			req.Protocol, req.Host, req.Path = u.Scheme, u.Host, strings.TrimPrefix(u.Path, "/")
+			if u.User != nil {
+				req.Username = u.User.Username()
+			}
+		}
+	}
+	return req, sc.Err()
+}
+
+func gitCredentialGet(g Globals, req gitRequest, w io.Writer) error {
+	if req.Host == "" {
+		return nil
+	}
+	_, vault, session, err := openVault(g, newTTYPrompter(), store.ReadOnly)
+	if err != nil {
+		return err
+	}
+	session.Close()
+
+	i := findGitEntry(vault, req)
+	if i < 0 {
+		return nil // Let git try the next helper or prompt
+	}
+	e := vault.Entries[i]
+	if e.Username != "" {
+		fmt.Fprintf(w, "username=%s\n", e.Username)
+	}
+	fmt.Fprintf(w, "password=%s\n", e.Password)
+	return nil
+}
+
+// gitCredentialStore saves credentials git reports as working, updating the
+// entry they came from or creating one for the host.
+func gitCredentialStore(g Globals, req gitRequest) error {
+	if req.Host == "" || req.Password == "" {
+		return nil
+	}
+	_, vault, session, err := openVault(g, newTTYPrompter(), store.ReadWrite)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	now := time.Now()
+	if i := findGitEntry(vault, req); i >= 0 {
+		e := &vault.Entries[i]
+		if e.Username == req.Username && e.Password == req.Password {
+			return nil // Already stored, which is the usual case after get
+		}
+		if e.Username == "" || e.Username == req.Username {
+			e.Username, e.Password, e.UpdatedAt = req.Username, req.Password, now
+			return session.Save(vault)
+		}
+	}
+
+	title := req.Host
+	if req.Path != "" {
+		title += "/" + strings.TrimSuffix(req.Path, ".git")
+	}
+	protocol := req.Protocol
+	if protocol == "" {
+		protocol = "https"
+	}
+	u := &url.URL{Scheme: protocol, Host: req.Host}
+	if req.Path != "" {
+		u.Path = "/" + req.Path
+	}
+	vault.Entries = append(vault.Entries, model.Entry{
+		ID:        model.NewID(),
+		Title:     title,
+		Username:  req.Username,
+		Password:  req.Password,
+		URL:       u.String(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	return session.Save(vault)
+}
+
+// gitCredentialErase deletes the entry matching credentials git reports as
+// rejected. Only an entry holding exactly the rejected password is removed,
+// so a request without one never deletes anything.
+func gitCredentialErase(g Globals, req gitRequest) error {
+	if req.Host == "" || req.Password == "" {
+		return nil
+	}
+	_, vault, session, err := openVault(g, newTTYPrompter(), store.ReadWrite)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	i := findGitEntry(vault, req)
+	if i < 0 || vault.Entries[i].Password != req.Password {
+		return nil
+	}
+	vault.Remove(i)
+	return session.Save(vault)
+}
+
+// findGitEntry returns the index of the entry whose URL best matches req,
+// or -1. The host (with port) must match, and so must the scheme if the
+// entry URL gives one. An entry path must be a prefix of the request path;
+// the longest matching path wins, then the most recently updated entry.
+// Requests without a path prefer entries without one. If req names a
+// user, entries for other users are skipped.
+func findGitEntry(vault *model.Vault, req gitRequest) int {
+	best, bestScore := -1, -2
+	reqPath := strings.Trim(req.Path, "/")
+	for i, e := range vault.Entries {
+		u, ok := parseEntryURL(e.URL)
+		if !ok || !strings.EqualFold(u.Host, req.Host) {
+			continue
+		}
+		if u.Scheme != "" && req.Protocol != "" && !strings.EqualFold(u.Scheme, req.Protocol) {
+			continue
+		}
+		if req.Username != "" && e.Username != "" && e.Username != req.Username {
+			continue
+		}
+
+		path := strings.Trim(u.Path, "/")
+		score := len(path)
+		switch {
+		case reqPath == "":
+			// git only sends paths with credential.useHttpPath; without
+			// one, prefer entries for the whole host
+			score = 0
+			if path != "" {
+				score = -1
+			}
+		case path == "", reqPath == path, strings.HasPrefix(reqPath, path+"/"):
+		case strings.TrimSuffix(reqPath, ".git") == path:
+			// git sends repo paths with ".git"; entries may leave it off
+		default:
+			continue
+		}
+		if score > bestScore || (score == bestScore && e.UpdatedAt.After(vault.Entries[best].UpdatedAt)) {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+// parseEntryURL parses the URL field of an entry, which may leave out the
+// scheme ("github.com/org").
+func parseEntryURL(s string) (*url.URL, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, false
+	}
+	if !strings.Contains(s, "://") {
+		u, err := url.Parse("//" + s)
+		return u, err == nil && u.Host != ""
+	}
+	u, err := url.Parse(s)
+	return u, err == nil && u.Host != ""
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fezcode/atlas.compass/pkg/model"
+)
+
+func TestReadGitRequest(t *testing.T) {
+	input := "protocol=https\nhost=example.com:8443\npath=team/app.git\nusername=alice\npassword=a=b\n\nhost=ignored\n"
+	req, err := readGitRequest(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := gitRequest{Protocol: "https", Host: "example.com:8443", Path: "team/app.git", Username: "alice", Password: "a=b"}
+	if req != want {
+		t.Errorf("readGitRequest = %+v, want %+v", req, want)
+	}
+
+	req, err = readGitRequest(strings.NewReader("url=https://bob@example.com:8443/team/app.git\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = gitRequest{Protocol: "https", Host: "example.com:8443", Path: "team/app.git", Username: "bob"}
+	if req != want {
+		t.Errorf("readGitRequest(url) = %+v, want %+v", req, want)
+	}
+
+	if _, err := readGitRequest(strings.NewReader("protocol https\n")); err == nil {
+		t.Error("readGitRequest accepted a line without '='")
+	}
+}
+
+func TestFindGitEntry(t *testing.T) {
+	vault := &model.Vault{Entries: []model.Entry{
+		{Title: "host", URL: "https://github.com", Username: "alice"},
+		{Title: "org", URL: "github.com/acme"},
+		{Title: "repo", URL: "https://github.com/acme/widgets"},
+		{Title: "http", URL: "http://intranet.example"},
+		{Title: "port", URL: "https://git.example.com:8443/team"},
+		{Title: "no port", URL: "https://git.example.com/team"},
+		{Title: "whole host", URL: "https://git.example.com"},
+	}}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string // Title of the entry, or "" for none
+	}{
+		{"host only", "protocol=https\nhost=github.com\n", "host"},
+		{"url line", "url=https://github.com/acme/widgets\n", "repo"},
+		{".git suffix", "url=https://github.com/acme/widgets.git\n", "repo"},
+		{"path prefix", "url=https://github.com/acme/other.git\n", "org"},
+		{"prefix stops at slash", "url=https://github.com/acmecorp/x.git\n", "host"},
+		{"user from url", "url=https://bob@github.com/other/x.git\n", ""},
+		{"scheme mismatch", "protocol=https\nhost=intranet.example\n", ""},
+		{"scheme match", "protocol=http\nhost=intranet.example\n", "http"},
+		{"no scheme in request", "host=intranet.example\n", "http"},
+		{"port", "protocol=https\nhost=git.example.com:8443\npath=team/app.git\n", "port"},
+		{"without port", "protocol=https\nhost=git.example.com\npath=team/app.git\n", "no port"},
+		{"other port", "protocol=https\nhost=git.example.com:9999\n", ""},
+		{"no path prefers host entries", "protocol=https\nhost=git.example.com\n", "whole host"},
+		{"only a path entry", "protocol=https\nhost=git.example.com:8443\n", "port"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := readGitRequest(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			if i := findGitEntry(vault, req); i >= 0 {
+				got = vault.Entries[i].Title
+			}
+			if got != tt.want {
+				t.Errorf("findGitEntry = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/fezcode/atlas.compass/internal/crypto"
+	"github.com/fezcode/atlas.compass/internal/store"
+	"github.com/fezcode/atlas.compass/pkg/model"
+)
+
+// runInject renders a template in which {{ compass "<title|id>" "<field>" }}
+// stands for a vault field, and writes the result readable by the owner
+// only.
+func runInject(g Globals, args []string) error {
+	fs := flag.NewFlagSet("inject", flag.ContinueOnError)
+	in := fs.String("i", "", "template file (default stdin)")
+	out := fs.String("o", "", "output file, written with mode 0600 (default stdout)")
+	strict := fs.Bool("strict", false, "fail if a referenced entry does not exist, instead of leaving it empty")
+	dryRun := fs.Bool("dry-run", false, "list the references in the template without unlocking the vault")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errors.New("usage: atlas.compass inject [-i template] [-o output] [-strict] [-dry-run]")
+	}
+
+	name, src, err := readTemplate(*in)
+	if err != nil {
+		return err
+	}
+
+	// Parse with a stand-in so syntax errors show up before the prompt
+	tmpl, err := template.New(name).Funcs(template.FuncMap{"compass": func(string, string) string { return "" }}).Parse(string(src))
+	if err != nil {
+		return err
+	}
+	refs := templateRefs(tmpl)
+	for _, r := range refs {
+		if r.field != "" {
+			if _, err := entryField(model.Entry{}, r.field); err != nil {
+				return fmt.Errorf("%s: %w", r.pos, err)
+			}
+		}
+	}
+
+	if *dryRun {
+		for _, r := range refs {
+			if r.entry == "" || r.field == "" {
+				fmt.Printf("%s\t(computed reference)\n", r.pos)
+				continue
+			}
+			fmt.Printf("%s\t%s/%s\n", r.pos, r.entry, r.field)
+		}
+		return nil
+	}
+
+	p := newPrompter()
+	if *in == "" {
+		p = newTTYPrompter() // stdin holds the template
+	}
+	_, vault, session, err := openVault(g, p, store.ReadOnly)
+	if err != nil {
+		return err
+	}
+	session.Close()
+
+	tmpl.Funcs(template.FuncMap{"compass": func(ref, field string) (string, error) {
+		i, err := vault.Find(ref)
+		if errors.Is(err, model.ErrEntryNotFound) && !*strict {
+			fmt.Fprintf(os.Stderr, "Warning: %v; left empty\n", err)
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return entryField(vault.Entries[i], field)
+	}})
+
+	var buf bytes.Buffer
+	defer func() { crypto.Wipe(buf.Bytes()) }()
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		// The error wraps the one returned by compass, so exit codes still
+		// tell a missing entry from an ambiguous one
+		return err
+	}
+
+	if *out == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return writePrivate(*out, buf.Bytes())
+}
+
+func readTemplate(path string) (string, []byte, error) {
+	if path == "" {
+		src, err := io.ReadAll(os.Stdin)
+		return "stdin", src, err
+	}
+	src, err := os.ReadFile(path)
+	return filepath.Base(path), src, err
+}
+
+// writePrivate replaces the file at path with data, readable and writable
+// by the owner only. A partly written file never takes its place.
+func writePrivate(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op after the rename
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// templateRef is one call of compass in a template. entry or field is empty
+// when the template computes it instead of giving a string literal.
+type templateRef struct {
+	pos          string
+	entry, field string
+}
+
+// templateRefs finds every call of compass in tmpl, in the order they
+// appear, without executing it.
+func templateRefs(tmpl *template.Template) []templateRef {
+	var refs []templateRef
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		tree := t.Tree
+		var walk func(parse.Node)
+		walk = func(n parse.Node) {
+			switch n := n.(type) {
+			case *parse.ListNode:
+				if n == nil {
+					return
+				}
+				for _, c := range n.Nodes {
+					walk(c)
+				}
+			case *parse.ActionNode:
+				walk(n.Pipe)
+			case *parse.IfNode:
+				walk(&n.BranchNode)
+			case *parse.RangeNode:
+				walk(&n.BranchNode)
+			case *parse.WithNode:
+				walk(&n.BranchNode)
+			case *parse.BranchNode:
+				walk(n.Pipe)
+				walk(n.List)
+				walk(n.ElseList)
+			case *parse.TemplateNode:
+				walk(n.Pipe)
+			case *parse.PipeNode:
+				if n == nil {
+					return
+				}
+				for _, c := range n.Cmds {
+					walk(c)
+				}
+			case *parse.CommandNode:
+				if id, ok := n.Args[0].(*parse.IdentifierNode); ok && id.Ident == "compass" {
+					loc, _ := tree.ErrorContext(n)
+					r := templateRef{pos: loc}
+					if len(n.Args) > 1 {
+						r.entry = stringArg(n.Args[1])
+					}
+					if len(n.Args) > 2 {
+						r.field = stringArg(n.Args[2])
+					}
+					refs = append(refs, r)
+				}
+				for _, a := range n.Args {
+					walk(a)
+				}
+			}
+		}
+		walk(tree.Root)
+	}
+	return refs
+}
+
+func stringArg(n parse.Node) string {
+	if s, ok := n.(*parse.StringNode); ok {
+		return s.Text
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/fezcode/atlas.compass/internal/config"
+	"github.com/fezcode/atlas.compass/internal/crypto"
+	"github.com/fezcode/atlas.compass/internal/store"
+)
+
+func runKeyfile(g Globals, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: atlas.compass keyfile <generate|enable|disable> [path]")
+	}
+	switch args[0] {
+	case "generate":
+		if len(args) != 2 {
+			return errors.New("usage: atlas.compass keyfile generate <path>")
+		}
+		if err := crypto.GenerateKeyfile(args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d random bytes to %s.\n", crypto.KeyfileSize, args[1])
+		fmt.Println("Keep a backup: without it, a vault that requires this keyfile cannot be opened.")
+		return nil
+	case "enable":
+		if len(args) != 2 {
+			return errors.New("usage: atlas.compass keyfile enable <path>")
+		}
+		return setKeyfile(g, args[1])
+	case "disable":
+		return setKeyfile(g, "")
+	}
+	return fmt.Errorf("unknown keyfile command %q", args[0])
+}
+
+// setKeyfile rewraps the master password slot so that it requires the
+// keyfile at path, or no keyfile if path is empty, and records the choice
+// in the config.
+func setKeyfile(g Globals, path string) error {
+	if !store.Exists() {
+		return errors.New("no vault found")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	var next []byte
+	if path != "" {
+		if path, err = filepath.Abs(path); err != nil {
+			return err
+		}
+		if next, err = crypto.ReadKeyfile(path); err != nil {
+			return fmt.Errorf("cannot read keyfile: %w", err)
+		}
+	}
+
+	p := newPrompter()
+	pass, err := g.masterPassword(p, "Master password: ")
+	if err != nil {
+		return err
+	}
+
+	current, err := passwordCredential(g, cfg, pass)
+	if err != nil {
+		return err
+	}
+	defer current.Wipe()
+	_, session, err := store.Load(current, store.ReadWrite)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	nextCred := crypto.PasswordCredential(pass).WithKeyfile(next)
+	defer nextCred.Wipe()
+	if err := session.ChangePassword(current, nextCred, slotParams(cfg, session)); err != nil {
+		return err
+	}
+
+	v := cfg.VaultConfig(store.Name())
+	v.Keyfile = path
+	cfg.SetVaultConfig(store.Name(), v)
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+
+	if path == "" {
+		fmt.Println("Keyfile disabled. The master password alone unlocks the vault.")
+	} else {
+		fmt.Printf("Keyfile enabled. The vault now needs the master password and %s.\n", path)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// PasswordEnv is the environment variable read by -password-env.
+const PasswordEnv = "ATLAS_COMPASS_PASSWORD"
+
+// PasswordSource says where commands get the master password from instead
+// of prompting for it. At most one source may be set.
+type PasswordSource struct {
+	Stdin   bool   // First line of stdin
+	FD      int    // First line read from this inherited file descriptor; -1 if unset
+	File    string // First line of this file
+	Command string // First line printed by this shell command
+	Env     bool   // PasswordEnv; insecure, so it must be asked for explicitly
+}
+
+func (s PasswordSource) count() int {
+	n := 0
+	for _, set := range []bool{s.Stdin, s.FD >= 0, s.File != "", s.Command != "", s.Env} {
+		if set {
+			n++
+		}
+	}
+	return n
+}
+
+// masterPassword returns the master password from the configured source,
+// or prompts for it if there is none.
+func (g Globals) masterPassword(p *prompter, prompt string) (string, error) {
+	s := g.Password
+	switch {
+	case s.count() > 1:
+		return "", errors.New("give only one of -password-stdin, -password-fd, -password-file, -password-command, -password-env")
+	case s.Stdin, s.FD == 0:
+		if p.stdin() {
+			return p.line("")
+		}
+		// Read no further than the line, leaving the rest of stdin to
+		// whatever reads it next
+		return firstLine(byteReader{os.Stdin}, "-password-stdin")
+	case s.FD > 0:
+		f := os.NewFile(uintptr(s.FD), "password-fd")
+		if f == nil {
+			return "", fmt.Errorf("invalid -password-fd %d", s.FD)
+		}
+		defer f.Close()
+		return firstLine(f, fmt.Sprintf("-password-fd %d", s.FD))
+	case s.File != "":
+		return passwordFromFile(s.File)
+	case s.Command != "":
+		return passwordFromCommand(s.Command)
+	case s.Env:
+		pass, ok := os.LookupEnv(PasswordEnv)
+		if !ok {
+			return "", fmt.Errorf("-password-env given but %s is not set", PasswordEnv)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: reading the master password from %s. Other processes of this user can read it.\n", PasswordEnv)
+		return pass, nil
+	}
+
+	if _, ok := os.LookupEnv(PasswordEnv); ok {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring %s; pass -password-env to use it.\n", PasswordEnv)
+	}
+	return p.secret(prompt)
+}
+
+// firstLine reads up to the first newline of r. name describes r in errors.
+func firstLine(r io.Reader, name string) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		if errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("%s: no password given", name)
+		}
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// byteReader reads one byte at a time, so a bufio.Reader on top of it
+// never reads past what it returns.
+type byteReader struct{ r io.Reader }
+
+func (b byteReader) Read(p []byte) (int, error) {
+	if len(p) > 1 {
+		p = p[:1]
+	}
+	return b.r.Read(p)
+}
+
+func passwordFromFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if fi, err := f.Stat(); err == nil && runtime.GOOS != "windows" && fi.Mode().Perm()&0077 != 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %s is readable by other users (mode %04o); chmod 600 it.\n", path, fi.Mode().Perm())
+	}
+	return firstLine(f, path)
+}
+
+// passwordFromCommand runs command in the shell and returns the first line
+// it prints. The command inherits stdin and stderr, so it can prompt, as
+// pinentry-style programs do.
+func passwordFromCommand(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("-password-command: %w", err)
+	}
+	line, _, _ := strings.Cut(string(out), "\n")
+	line = strings.TrimRight(line, "\r")
+	if line == "" {
+		return "", errors.New("-password-command: no password printed")
+	}
+	return line, nil
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// prompter reads answers for interactive commands. On a terminal it prompts
+// on stderr and hides secrets; otherwise it reads one line per answer from
+// stdin, so answers can be piped in.
+type prompter struct {
+	in  *bufio.Reader
+	fd  uintptr
+	tty bool
+	err error // Returned by every read if there is nothing to read from
+}
+
+func newPrompter() *prompter {
+	return &prompter{
+		in:  bufio.NewReader(os.Stdin),
+		fd:  os.Stdin.Fd(),
+		tty: term.IsTerminal(os.Stdin.Fd()),
+	}
+}
+
+// newTTYPrompter prompts on the controlling terminal, for commands whose
+// stdin carries something else, such as a credential helper protocol.
+// Without a terminal, reads fail, which only matters if the agent or a
+// -password-* flag does not supply the password.
+func newTTYPrompter() *prompter {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return &prompter{err: errors.New("no terminal to ask for the master password on: start the agent or give a -password-* flag")}
+	}
+	return &prompter{in: bufio.NewReader(tty), fd: tty.Fd(), tty: true}
+}
+
+// stdin reports whether p reads from stdin.
+func (p *prompter) stdin() bool {
+	return p.err == nil && p.fd == os.Stdin.Fd()
+}
+
+// line reads one line of visible input.
+func (p *prompter) line(prompt string) (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	if p.tty {
+		fmt.Fprint(os.Stderr, prompt)
+	}
+	s, err := p.in.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && s != "") {
+		return "", err
+	}
+	return strings.TrimRight(s, "\r\n"), nil
+}
+
+// secret reads one line without echoing it on a terminal.
+func (p *prompter) secret(prompt string) (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	if !p.tty {
+		return p.line(prompt)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	b, err := term.ReadPassword(p.fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// newPassword asks for a new password twice and checks that both match.
+func (p *prompter) newPassword(prompt string) (string, error) {
+	pass, err := p.secret(prompt)
+	if err != nil {
+		return "", err
+	}
+	if pass == "" {
+		return "", errors.New("password cannot be empty")
+	}
+	confirm, err := p.secret("Retype: ")
+	if err != nil {
+		return "", err
+	}
+	if pass != confirm {
+		return "", errors.New("passwords do not match")
+	}
+	return pass, nil
+}
+
+// entryPassword asks for the password of an entry. On a terminal it is
+// asked for twice; piped input gives it once. It may be empty.
+func (p *prompter) entryPassword() (string, error) {
+	pass, err := p.secret("Entry password: ")
+	if err != nil || !p.tty {
+		return pass, err
+	}
+	confirm, err := p.secret("Retype: ")
+	if err != nil {
+		return "", err
+	}
+	if pass != confirm {
+		return "", errors.New("passwords do not match")
+	}
+	return pass, nil
+}
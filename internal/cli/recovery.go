@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fezcode/atlas.compass/internal/config"
+	"github.com/fezcode/atlas.compass/internal/crypto"
+	"github.com/fezcode/atlas.compass/internal/store"
+)
+
+func runRecovery(g Globals, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: atlas.compass recovery <split|combine> [flags]")
+	}
+	switch args[0] {
+	case "split":
+		return runRecoverySplit(g, args[1:])
+	case "combine":
+		return runRecoveryCombine(g, args[1:])
+	}
+	return fmt.Errorf("unknown recovery command %q", args[0])
+}
+
+// runRecoverySplit adds a new recovery key slot and prints the key as
+// Shamir shares instead of words, so no single holder can unlock the vault.
+func runRecoverySplit(g Globals, args []string) error {
+	fs := flag.NewFlagSet("recovery split", flag.ContinueOnError)
+	n := fs.Int("n", 5, "number of shares to create")
+	k := fs.Int("k", 3, "number of shares needed to recover")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *k < 2 || *k > *n || *n > 255 {
+		return fmt.Errorf("need 2 <= k <= n <= 255")
+	}
+	if !store.Exists() {
+		return errors.New("no vault found")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	_, session, err := unlockVault(g, cfg, newPrompter(), store.ReadWrite)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	key, err := crypto.NewRecoveryKey()
+	if err != nil {
+		return err
+	}
+	defer crypto.Wipe(key)
+	shares, err := crypto.SplitSecret(key, *n, *k)
+	if err != nil {
+		return err
+	}
+
+	if err := session.AddSlot(crypto.RecoveryCredential(key), slotParams(cfg, session)); err != nil {
+		return err
+	}
+
+	fmt.Printf("Added a recovery key slot. Any %d of these %d shares unlock the vault.\n", *k, *n)
+	fmt.Println("Give each share to a different holder. They are shown ONLY ONCE.")
+	fmt.Println()
+	for _, s := range shares {
+		fmt.Println(s)
+	}
+	return nil
+}
+
+// runRecoveryCombine reconstructs a recovery key from shares and uses it to
+// set a new master password, or prints it as words with -words.
+func runRecoveryCombine(g Globals, args []string) error {
+	fs := flag.NewFlagSet("recovery combine", flag.ContinueOnError)
+	words := fs.Bool("words", false, "print the recovery key words instead of setting a new password")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !store.Exists() {
+		return errors.New("no vault found")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	p := newPrompter()
+	var shares []crypto.Share
+	for len(shares) == 0 || len(shares) < int(shares[0].Threshold) {
+		text, err := p.line(fmt.Sprintf("Share %d: ", len(shares)+1))
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		share, err := crypto.ParseShare(text)
+		if err != nil {
+			return fmt.Errorf("share %d: %w", len(shares)+1, err)
+		}
+		shares = append(shares, share)
+	}
+
+	key, err := crypto.CombineShares(shares)
+	if err != nil {
+		return err
+	}
+
+	defer crypto.Wipe(key)
+
+	_, session, err := store.Load(crypto.RecoveryCredential(key), store.ReadWrite)
+	if err != nil {
+		return fmt.Errorf("shares do not unlock this vault: %w", err)
+	}
+	defer session.Close()
+
+	if *words {
+		fmt.Println(strings.Join(crypto.EncodeMnemonic(key), " "))
+		return nil
+	}
+
+	newPass, err := p.newPassword("New master password: ")
+	if err != nil {
+		return err
+	}
+	next, err := passwordCredential(g, cfg, newPass)
+	if err != nil {
+		return err
+	}
+	defer next.Wipe()
+	if err := session.ResetPassword(next, slotParams(cfg, session)); err != nil {
+		return err
+	}
+	fmt.Println("Vault unlocked with recovery shares. New master password set.")
+	return nil
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/fezcode/atlas.compass/internal/store"
+	"github.com/fezcode/atlas.compass/pkg/model"
+)
+
+// envRefs collects repeated -env NAME=entry:<title|id>/<field> flags.
+type envRefs []envRef
+
+type envRef struct {
+	name, entry, field string
+}
+
+func (r *envRefs) String() string { return "" }
+
+func (r *envRefs) Set(s string) error {
+	ref, err := parseEnvRef(s)
+	if err != nil {
+		return err
+	}
+	*r = append(*r, ref)
+	return nil
+}
+
+// parseEnvRef parses NAME=entry:<title|id>/<field>. The field follows the
+// last slash, so titles may contain slashes.
+func parseEnvRef(s string) (envRef, error) {
+	name, target, ok := strings.Cut(s, "=")
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return envRef{}, errors.New("want NAME=entry:<title|id>/<field>")
+	}
+	target, ok = strings.CutPrefix(target, "entry:")
+	if !ok {
+		return envRef{}, errors.New("the value must start with entry:")
+	}
+	i := strings.LastIndex(target, "/")
+	if i <= 0 || i == len(target)-1 {
+		return envRef{}, errors.New("want NAME=entry:<title|id>/<field>")
+	}
+	ref := envRef{name: name, entry: target[:i], field: target[i+1:]}
+	if _, err := entryField(model.Entry{}, ref.field); err != nil {
+		return envRef{}, err
+	}
+	return ref, nil
+}
+
+// runRun starts a command with vault fields in its environment. Every
+// reference is resolved before the command starts; the secrets reach only
+// the child's environment, never the shell or the disk.
+func runRun(g Globals, args []string) error {
+	var refs envRefs
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.Var(&refs, "env", "set NAME to a vault field, as NAME=entry:<title|id>/<field> (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	argv := fs.Args()
+	if len(argv) == 0 {
+		return errors.New("usage: atlas.compass run -env NAME=entry:<title|id>/<field> [-env ...] -- command [args]")
+	}
+	if len(refs) == 0 {
+		return errors.New("no -env given: nothing to inject")
+	}
+
+	// The child gets stdin, so ask for the master password on the terminal
+	_, vault, session, err := openVault(g, newTTYPrompter(), store.ReadOnly)
+	if err != nil {
+		return err
+	}
+	session.Close()
+
+	env := os.Environ()
+	for _, ref := range refs {
+		i, err := vault.Find(ref.entry)
+		if err != nil {
+			return fmt.Errorf("-env %s: %w", ref.name, err)
+		}
+		value, _ := entryField(vault.Entries[i], ref.field)
+		env = append(env, ref.name+"="+value) // Later entries win over inherited ones
+	}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Env = env
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	// Ctrl+C already reaches the child through the terminal; only keep it
+	// from killing us first. Termination requests are passed on.
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	go func() {
+		for s := range sig {
+			if s != os.Interrupt {
+				cmd.Process.Signal(s)
+			}
+		}
+	}()
+
+	err = cmd.Wait()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return exitCode(exit.ExitCode())
+	}
+	return err
+}
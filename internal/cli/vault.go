@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/fezcode/atlas.compass/internal/agent"
+	"github.com/fezcode/atlas.compass/internal/config"
+	"github.com/fezcode/atlas.compass/internal/crypto"
+	"github.com/fezcode/atlas.compass/internal/store"
+	"github.com/fezcode/atlas.compass/pkg/model"
+)
+
+// unlockVault opens the vault in the given mode with the key held by the
+// agent, if one is running, or else asks for the master password and hands
+// the key to the agent. The caller must Close the session.
+func unlockVault(g Globals, cfg *config.Config, p *prompter, mode store.Mode) (*model.Vault, *store.Session, error) {
+	if !g.NoAgent {
+		vault, session, err := agent.Open(mode)
+		if err == nil {
+			return vault, session, nil
+		}
+		if errors.Is(err, store.ErrInUse) {
+			return nil, nil, err // Do not ask for a password that cannot help
+		}
+	}
+
+	pass, err := g.masterPassword(p, "Master password: ")
+	if err != nil {
+		return nil, nil, err
+	}
+	cred, err := passwordCredential(g, cfg, pass)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cred.Wipe()
+	vault, session, err := store.Load(cred, mode)
+	if err != nil {
+		return nil, nil, err
+	}
+	upgradeVault(cfg, session, cred)
+	if !g.NoAgent {
+		agent.AddSession(session) // Fails harmlessly if no agent is running
+	}
+	return vault, session, nil
+}
+
+// upgradeVault brings a vault opened for writing up to the config, like the
+// terminal UI does on unlock: the slot cred opened is rewrapped if its key
+// derivation is below the configured minimum, and the payload re-encrypted
+// if another cipher is configured. Failures are reported but do not stop
+// the command.
+func upgradeVault(cfg *config.Config, session *store.Session, cred crypto.Credential) {
+	if session.ReadOnly() {
+		return
+	}
+
+	min := cfg.KDFParams(store.Name())
+	if stored := session.Params(); stored.Below(min) {
+		params := stored.Raise(min)
+		if err := session.Rewrap(cred, params); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: cannot upgrade key derivation: %v\n", err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Vault re-encrypted with stronger key derivation (%s).\n", params)
+		}
+	}
+
+	want := cfg.Cipher(store.Name())
+	if want == nil {
+		return
+	}
+	if have, err := store.Cipher(); err != nil || have.ID() == want.ID() {
+		return
+	}
+	if err := session.SetCipher(want); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cannot switch cipher: %v\n", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Vault re-encrypted with %s.\n", want)
+}
+
+// slotParams returns the key derivation parameters for slots written in
+// session: those of the slot it was unlocked with, raised to the configured
+// minimum, so replacing a slot never weakens it.
+func slotParams(cfg *config.Config, session *store.Session) crypto.Params {
+	return session.Params().Raise(cfg.KDFParams(store.Name()))
+}
+
+// passwordCredential builds the master password credential, mixing in the
+// keyfile from --keyfile or the vault config when one is set.
+func passwordCredential(g Globals, cfg *config.Config, pass string) (crypto.Credential, error) {
+	cred := crypto.PasswordCredential(pass)
+	path := cfg.KeyfilePath(store.Name(), g.Keyfile)
+	if path == "" {
+		return cred, nil
+	}
+	digest, err := crypto.ReadKeyfile(path)
+	if err != nil {
+		return cred, fmt.Errorf("cannot read keyfile %s: %w", path, err)
+	}
+	return cred.WithKeyfile(digest), nil
+}
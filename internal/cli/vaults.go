@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fezcode/atlas.compass/internal/store"
+)
+
+// runVaults lists the vaults in ~/.atlas, marking the selected one.
+func runVaults(g Globals, args []string) error {
+	if len(args) > 0 {
+		return errors.New("usage: atlas.compass vaults")
+	}
+	names, err := store.ListVaults()
+	if err != nil {
+		return err
+	}
+	current := store.Name()
+	found := false
+	for _, name := range names {
+		mark := " "
+		if name == current {
+			mark, found = "*", true
+		}
+		fmt.Printf("%s %s\n", mark, name)
+	}
+	if !found {
+		// A vault file elsewhere, or a name without a file yet
+		note := ""
+		if !store.Exists() {
+			note = " (not created yet)"
+		}
+		fmt.Printf("* %s%s\n", current, note)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+// Package clipboard puts secrets on the clipboard and takes them off again.
+package clipboard
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Clipboard is somewhere text can be copied to, such as the system clipboard.
+type Clipboard interface {
+	ReadAll() (string, error)
+	WriteAll(text string) error
+}
+
+// Backend names accepted by New.
+const (
+	BackendAuto   = "auto"
+	BackendSystem = "system"
+	BackendOSC52  = "osc52"
+	BackendTmux   = "tmux"
+)
+
+// Backends lists the names accepted by New.
+var Backends = []string{BackendAuto, BackendSystem, BackendOSC52, BackendTmux}
+
+// ErrReadUnsupported is returned by backends that can only write.
+var ErrReadUnsupported = errors.New("clipboard cannot be read")
+
+// New returns the named clipboard backend. An empty name or "auto" picks
+// one with Detect.
+func New(name string) (Clipboard, error) {
+	switch strings.ToLower(name) {
+	case "", BackendAuto:
+		return Detect(), nil
+	case BackendSystem:
+		return System{}, nil
+	case BackendOSC52:
+		return OSC52{}, nil
+	case BackendTmux:
+		return Tmux{}, nil
+	}
+	return nil, fmt.Errorf("unknown clipboard backend %q (want one of %s)", name, strings.Join(Backends, ", "))
+}
+
+// Detect picks the backend most likely to reach the user's clipboard: the
+// system clipboard when one is available, else the tmux buffer when running
+// inside tmux, else OSC 52, which works over SSH in most terminals.
+func Detect() Clipboard {
+	switch {
+	case systemAvailable():
+		return System{}
+	case os.Getenv("TMUX") != "":
+		return Tmux{}
+	default:
+		return OSC52{}
+	}
+}
+
+// Guard copies values to a Clipboard and clears them after a timeout. It
+// only clears the clipboard if it still holds the value that was copied, so
+// anything the user copied since is left alone. Only a hash of the value is
+// kept.
+type Guard struct {
+	cb      Clipboard
+	sum     [sha256.Size]byte
+	pending bool
+	expires time.Time // Zero if the value is only cleared by Clear
+}
+
+// NewGuard returns a Guard for cb.
+func NewGuard(cb Clipboard) *Guard {
+	return &Guard{cb: cb}
+}
+
+// Copy writes text to the clipboard, to be cleared after ttl. A ttl of zero
+// leaves it until Clear is called.
+func (g *Guard) Copy(text string, ttl time.Duration) error {
+	if err := g.cb.WriteAll(text); err != nil {
+		return err
+	}
+	g.sum = sha256.Sum256([]byte(text))
+	g.pending = true
+	g.expires = time.Time{}
+	if ttl > 0 {
+		g.expires = time.Now().Add(ttl)
+	}
+	return nil
+}
+
+// Pending reports whether a copied value is waiting to be cleared.
+func (g *Guard) Pending() bool {
+	return g != nil && g.pending
+}
+
+// Remaining returns the time until the copied value is cleared, or zero if
+// nothing is pending or no timeout was set.
+func (g *Guard) Remaining() time.Duration {
+	if !g.Pending() || g.expires.IsZero() {
+		return 0
+	}
+	return max(time.Until(g.expires), 0)
+}
+
+// Expired reports whether the copied value is due to be cleared.
+func (g *Guard) Expired() bool {
+	return g.Pending() && !g.expires.IsZero() && !time.Now().Before(g.expires)
+}
+
+// Clear empties the clipboard if it still holds the copied value and
+// reports whether it did. If the clipboard cannot be read it is cleared
+// anyway, since the secret may still be on it.
+func (g *Guard) Clear() (bool, error) {
+	if !g.Pending() {
+		return false, nil
+	}
+	g.pending = false
+
+	current, err := g.cb.ReadAll()
+	if err == nil {
+		sum := sha256.Sum256([]byte(current))
+		if subtle.ConstantTimeCompare(sum[:], g.sum[:]) != 1 {
+			return false, nil // The user copied something else since
+		}
+	}
+	if err := g.cb.WriteAll(""); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,127 @@
+package clipboard
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// fakeClipboard holds text in memory. With readErr set it cannot be read,
+// like OSC52.
+type fakeClipboard struct {
+	text    string
+	writes  int
+	readErr error
+}
+
+func (f *fakeClipboard) ReadAll() (string, error) {
+	if f.readErr != nil {
+		return "", f.readErr
+	}
+	return f.text, nil
+}
+
+func (f *fakeClipboard) WriteAll(text string) error {
+	f.text = text
+	f.writes++
+	return nil
+}
+
+func TestGuardClearsCopiedValue(t *testing.T) {
+	cb := &fakeClipboard{}
+	g := NewGuard(cb)
+	if err := g.Copy("hunter2", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if cb.text != "hunter2" {
+		t.Fatalf("clipboard = %q after Copy, want %q", cb.text, "hunter2")
+	}
+
+	cleared, err := g.Clear()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cleared || cb.text != "" {
+		t.Errorf("Clear = %v, clipboard %q; want cleared and empty", cleared, cb.text)
+	}
+	if g.Pending() {
+		t.Error("still pending after Clear")
+	}
+
+	// A second Clear has nothing left to do
+	writes := cb.writes
+	if cleared, err := g.Clear(); cleared || err != nil || cb.writes != writes {
+		t.Errorf("second Clear = %v, %v with %d writes; want nothing done", cleared, err, cb.writes-writes)
+	}
+}
+
+func TestGuardLeavesNewerValue(t *testing.T) {
+	cb := &fakeClipboard{}
+	g := NewGuard(cb)
+	if err := g.Copy("hunter2", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	cb.text = "something the user copied"
+
+	cleared, err := g.Clear()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cleared || cb.text != "something the user copied" {
+		t.Errorf("Clear = %v, clipboard %q; want it left alone", cleared, cb.text)
+	}
+	if g.Pending() {
+		t.Error("still pending after Clear")
+	}
+}
+
+func TestGuardClearsUnreadableClipboard(t *testing.T) {
+	cb := &fakeClipboard{readErr: fmt.Errorf("osc52: %w", ErrReadUnsupported)}
+	g := NewGuard(cb)
+	if err := g.Copy("hunter2", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	cleared, err := g.Clear()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cleared || cb.text != "" {
+		t.Errorf("Clear = %v, clipboard %q; want cleared and empty", cleared, cb.text)
+	}
+}
+
+func TestGuardTimeout(t *testing.T) {
+	g := NewGuard(&fakeClipboard{})
+	if g.Expired() || g.Remaining() != 0 {
+		t.Errorf("nothing copied: Expired = %v, Remaining = %v", g.Expired(), g.Remaining())
+	}
+
+	if err := g.Copy("hunter2", 0); err != nil {
+		t.Fatal(err)
+	}
+	if !g.Pending() {
+		t.Fatal("not pending after Copy")
+	}
+	if g.Expired() || g.Remaining() != 0 {
+		t.Errorf("zero ttl: Expired = %v, Remaining = %v; want false, 0", g.Expired(), g.Remaining())
+	}
+
+	if err := g.Copy("hunter2", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if r := g.Remaining(); r <= 0 || r > time.Hour {
+		t.Errorf("Remaining = %v, want within an hour", r)
+	}
+	if g.Expired() {
+		t.Error("Expired right after Copy")
+	}
+
+	if err := g.Copy("hunter2", time.Nanosecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if !g.Expired() || g.Remaining() != 0 {
+		t.Errorf("past ttl: Expired = %v, Remaining = %v; want true, 0", g.Expired(), g.Remaining())
+	}
+}
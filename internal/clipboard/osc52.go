@@ -0,0 +1,51 @@
+package clipboard
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+)
+
+// OSC52 copies by sending an OSC 52 escape sequence to the terminal, which
+// puts the text on the clipboard of the machine the terminal runs on. It
+// works over SSH, but the terminal must support it and it cannot be read
+// back.
+type OSC52 struct {
+	// Out receives the escape sequence. Nil means the controlling terminal.
+	Out io.Writer
+}
+
+func (OSC52) ReadAll() (string, error) {
+	return "", fmt.Errorf("osc52: %w", ErrReadUnsupported)
+}
+
+func (o OSC52) WriteAll(text string) error {
+	seq := osc52.New(text)
+	if text == "" {
+		seq = osc52.Clear()
+	}
+	switch {
+	case os.Getenv("TMUX") != "":
+		seq = seq.Tmux()
+	case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		seq = seq.Screen()
+	}
+
+	out := o.Out
+	if out == nil {
+		tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+		if err != nil {
+			out = os.Stdout
+		} else {
+			defer tty.Close()
+			out = tty
+		}
+	}
+	if _, err := seq.WriteTo(out); err != nil {
+		return fmt.Errorf("osc52: %w", err)
+	}
+	return nil
+}
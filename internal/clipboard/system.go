@@ -0,0 +1,40 @@
+package clipboard
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/atotto/clipboard"
+)
+
+// System is the operating system clipboard. On Linux and the BSDs it needs
+// a display and xclip, xsel or wl-clipboard.
+type System struct{}
+
+func (System) ReadAll() (string, error) {
+	text, err := clipboard.ReadAll()
+	if err != nil {
+		return "", fmt.Errorf("system clipboard: %w", err)
+	}
+	return text, nil
+}
+
+func (System) WriteAll(text string) error {
+	if err := clipboard.WriteAll(text); err != nil {
+		return fmt.Errorf("system clipboard: %w", err)
+	}
+	return nil
+}
+
+// systemAvailable reports whether the system clipboard can be used.
+func systemAvailable() bool {
+	if clipboard.Unsupported {
+		return false
+	}
+	switch runtime.GOOS {
+	case "darwin", "windows", "plan9":
+		return true
+	}
+	return os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != ""
+}
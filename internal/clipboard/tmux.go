@@ -0,0 +1,58 @@
+package clipboard
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Tmux copies into the tmux paste buffer. The -w flag of tmux 3.2 and later
+// also passes the text on to the outer terminal's clipboard.
+type Tmux struct{}
+
+// clearBuffer names the tmux buffer used to blank the outer clipboard.
+const clearBuffer = "atlas.compass-clear"
+
+func (Tmux) ReadAll() (string, error) {
+	out, err := tmux(nil, "save-buffer", "-")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (Tmux) WriteAll(text string) error {
+	if text == "" {
+		// An empty buffer cannot be loaded; drop the one we added instead,
+		// and overwrite the outer terminal's clipboard with a blank that is
+		// deleted from tmux again right away.
+		_, err := tmux(nil, "delete-buffer")
+		if _, werr := tmux([]byte(" "), "load-buffer", "-b", clearBuffer, "-w", "-"); werr == nil {
+			tmux(nil, "delete-buffer", "-b", clearBuffer)
+		}
+		return err
+	}
+	if _, err := tmux([]byte(text), "load-buffer", "-w", "-"); err == nil {
+		return nil
+	}
+	_, err := tmux([]byte(text), "load-buffer", "-")
+	return err
+}
+
+func tmux(stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command("tmux", args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("tmux: %s", msg)
+		}
+		return nil, fmt.Errorf("tmux: %w", err)
+	}
+	return out, nil
+}
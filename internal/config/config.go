@@ -0,0 +1,245 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fezcode/atlas.compass/internal/clipboard"
+	"github.com/fezcode/atlas.compass/internal/crypto"
+)
+
+const (
+	DirName  = ".atlas"
+	FileName = "compass.json"
+
+	// DefaultAutoLock is used when AutoLock is not set.
+	DefaultAutoLock = 5 * time.Minute
+
+	// DefaultClipboardClear is used when ClipboardClear is not set.
+	DefaultClipboardClear = 20 * time.Second
+)
+
+// KDF is the JSON form of crypto.Params.
+type KDF struct {
+	Time      uint32 `json:"time"`
+	MemoryKiB uint32 `json:"memory_kib"`
+	Threads   uint8  `json:"threads"`
+}
+
+// Params converts k to crypto.Params.
+func (k KDF) Params() crypto.Params {
+	return crypto.Params{Time: k.Time, Memory: k.MemoryKiB, Threads: k.Threads}
+}
+
+// FromParams converts crypto.Params to its JSON form.
+func FromParams(p crypto.Params) *KDF {
+	return &KDF{Time: p.Time, MemoryKiB: p.Memory, Threads: p.Threads}
+}
+
+// Vault holds settings that apply to a single vault.
+type Vault struct {
+	KDF *KDF `json:"kdf,omitempty"`
+
+	// Keyfile is the path of a second-factor keyfile mixed into the master
+	// password for this vault.
+	Keyfile string `json:"keyfile,omitempty"`
+
+	// Cipher names the payload cipher for this vault, e.g.
+	// "xchacha20-poly1305". Empty keeps whatever the vault file uses.
+	Cipher string `json:"cipher,omitempty"`
+}
+
+// Config is the user configuration stored in ~/.atlas/compass.json.
+type Config struct {
+	// KDF is the minimum Argon2id cost for every vault. Vaults written with
+	// weaker parameters are re-encrypted on unlock.
+	KDF *KDF `json:"kdf,omitempty"`
+
+	// AutoLock is how long the terminal UI may sit idle before it locks,
+	// as a Go duration such as "5m". "0" or "off" disables it.
+	AutoLock string `json:"auto_lock,omitempty"`
+
+	// ClipboardClear is how long a copied password stays on the clipboard,
+	// as a Go duration such as "20s". "0" or "off" keeps it until quit.
+	ClipboardClear string `json:"clipboard_clear,omitempty"`
+
+	// Clipboard picks where copied values go: "system", "osc52", "tmux", or
+	// "auto" (the default) to detect one.
+	Clipboard string `json:"clipboard,omitempty"`
+
+	// Vaults holds per-vault overrides keyed by vault name, or by absolute
+	// path for vault files outside ~/.atlas.
+	Vaults map[string]Vault `json:"vaults,omitempty"`
+}
+
+// Path returns the full path to the config file.
+func Path() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, DirName, FileName), nil
+}
+
+// Load reads the config file. A missing file yields an empty config.
+func Load() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+func (c *Config) validate() error {
+	if _, err := parseDuration(c.AutoLock, DefaultAutoLock); err != nil {
+		return fmt.Errorf("auto_lock: %w", err)
+	}
+	if _, err := parseDuration(c.ClipboardClear, DefaultClipboardClear); err != nil {
+		return fmt.Errorf("clipboard_clear: %w", err)
+	}
+	if _, err := clipboard.New(c.Clipboard); err != nil {
+		return fmt.Errorf("clipboard: %w", err)
+	}
+	if c.KDF != nil {
+		if err := c.KDF.Params().Validate(); err != nil {
+			return fmt.Errorf("kdf: %w", err)
+		}
+	}
+	for name, v := range c.Vaults {
+		if v.KDF != nil {
+			if err := v.KDF.Params().Validate(); err != nil {
+				return fmt.Errorf("vaults.%s.kdf: %w", name, err)
+			}
+		}
+		if v.Cipher != "" {
+			if _, err := crypto.CipherByName(v.Cipher); err != nil {
+				return fmt.Errorf("vaults.%s.cipher: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Save writes the config file.
+func (c *Config) Save() error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// VaultConfig returns the settings for the named vault.
+func (c *Config) VaultConfig(name string) Vault {
+	return c.Vaults[name]
+}
+
+// SetVaultConfig stores the settings for the named vault.
+func (c *Config) SetVaultConfig(name string, v Vault) {
+	if c.Vaults == nil {
+		c.Vaults = map[string]Vault{}
+	}
+	c.Vaults[name] = v
+}
+
+// KeyfilePath returns the keyfile to use for the named vault: override if
+// given (e.g. from --keyfile), else the configured one. Empty means none.
+func (c *Config) KeyfilePath(name, override string) string {
+	if override != "" {
+		return override
+	}
+	return c.VaultConfig(name).Keyfile
+}
+
+// KDFParams returns the minimum key derivation parameters for the named
+// vault: its own override if set, else the global setting, else the defaults.
+func (c *Config) KDFParams(name string) crypto.Params {
+	if v := c.VaultConfig(name); v.KDF != nil {
+		return v.KDF.Params()
+	}
+	if c.KDF != nil {
+		return c.KDF.Params()
+	}
+	return crypto.DefaultParams
+}
+
+// Cipher returns the configured payload cipher for the named vault, or nil
+// if none is set.
+func (c *Config) Cipher(name string) crypto.Cipher {
+	ci, err := crypto.CipherByName(c.VaultConfig(name).Cipher)
+	if err != nil {
+		return nil
+	}
+	return ci
+}
+
+// AutoLockAfter returns the idle time after which the terminal UI locks.
+// Zero means never.
+func (c *Config) AutoLockAfter() time.Duration {
+	d, err := parseDuration(c.AutoLock, DefaultAutoLock)
+	if err != nil {
+		return DefaultAutoLock
+	}
+	return d
+}
+
+// ClipboardClearAfter returns how long copied values stay on the clipboard.
+// Zero means until the app quits or locks.
+func (c *Config) ClipboardClearAfter() time.Duration {
+	d, err := parseDuration(c.ClipboardClear, DefaultClipboardClear)
+	if err != nil {
+		return DefaultClipboardClear
+	}
+	return d
+}
+
+// parseDuration parses a timeout setting: empty means def, "0" or "off"
+// means disabled.
+func parseDuration(s string, def time.Duration) (time.Duration, error) {
+	switch s {
+	case "":
+		return def, nil
+	case "0", "off":
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return d, nil
+}
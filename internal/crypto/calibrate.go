@@ -0,0 +1,48 @@
+package crypto
+
+import (
+	"time"
+)
+
+// MinCalibrateMem is the smallest memory cost Calibrate will start from.
+const MinCalibrateMem = 16 * 1024
+
+// Calibrate benchmarks DeriveKey on this machine and returns the strongest
+// parameters that still unlock in roughly the target duration. Memory cost is
+// raised first (up to maxMemory KiB) since it is what makes GPU attacks
+// expensive; the remaining budget is spent on iterations.
+// The measured duration of the returned parameters is also returned.
+func Calibrate(target time.Duration, maxMemory uint32, threads uint8) (Params, time.Duration) {
+	if threads == 0 {
+		threads = ArgonThreads
+	}
+	if maxMemory < MinCalibrateMem {
+		maxMemory = MinCalibrateMem
+	}
+
+	salt := make([]byte, SaltSize)
+	measure := func(p Params) time.Duration {
+		start := time.Now()
+		DeriveKey([]byte("calibration"), salt, p)
+		return time.Since(start)
+	}
+
+	p := Params{Time: 1, Memory: MinCalibrateMem, Threads: threads}
+	elapsed := measure(p)
+
+	// 1. Double memory while a single pass stays under half the target
+	for p.Memory*2 <= maxMemory && elapsed*2 <= target {
+		p.Memory *= 2
+		elapsed = measure(p)
+	}
+
+	// 2. Spend what is left on iterations; cost scales linearly with time
+	if elapsed > 0 && elapsed < target {
+		if iterations := uint32(target / elapsed); iterations > 1 {
+			p.Time = iterations
+			elapsed = measure(p)
+		}
+	}
+
+	return p, elapsed
+}
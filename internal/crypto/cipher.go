@@ -0,0 +1,84 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Cipher is an AEAD that can encrypt a vault payload. Its ID is recorded in
+// the vault file so the payload can be opened without any configuration.
+type Cipher interface {
+	ID() uint8
+	String() string
+	NonceSize() int
+	AEAD(key []byte) (cipher.AEAD, error)
+}
+
+var (
+	// AES256GCM is AES-256 in Galois/Counter Mode with 12-byte nonces.
+	AES256GCM Cipher = aesGCM{}
+
+	// XChaCha20Poly1305 uses 24-byte nonces, so random nonces stay safe no
+	// matter how often the vault is saved.
+	XChaCha20Poly1305 Cipher = xchacha{}
+)
+
+// DefaultCipher encrypts new vaults.
+var DefaultCipher = AES256GCM
+
+// Ciphers lists every supported cipher.
+var Ciphers = []Cipher{AES256GCM, XChaCha20Poly1305}
+
+// CipherByID returns the cipher recorded in a vault file.
+func CipherByID(id uint8) (Cipher, error) {
+	for _, c := range Ciphers {
+		if c.ID() == id {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported cipher id %d", id)
+}
+
+// CipherByName returns the cipher with the given name, as shown by String.
+func CipherByName(name string) (Cipher, error) {
+	names := make([]string, len(Ciphers))
+	for i, c := range Ciphers {
+		if strings.EqualFold(c.String(), name) {
+			return c, nil
+		}
+		names[i] = c.String()
+	}
+	return nil, fmt.Errorf("unknown cipher %q (want one of %s)", name, strings.Join(names, ", "))
+}
+
+type aesGCM struct{}
+
+func (aesGCM) ID() uint8      { return CipherAES256GCM }
+func (aesGCM) String() string { return "aes-256-gcm" }
+func (aesGCM) NonceSize() int { return NonceSize }
+
+func (aesGCM) AEAD(key []byte) (cipher.AEAD, error) {
+	return newGCM(key)
+}
+
+type xchacha struct{}
+
+func (xchacha) ID() uint8      { return CipherXChaCha20Poly1305 }
+func (xchacha) String() string { return "xchacha20-poly1305" }
+func (xchacha) NonceSize() int { return chacha20poly1305.NonceSizeX }
+
+func (xchacha) AEAD(key []byte) (cipher.AEAD, error) {
+	return chacha20poly1305.NewX(key)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,189 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// testParams keeps key derivation cheap in tests.
+var testParams = Params{Time: 1, Memory: 8, Threads: 1}
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCipherKnownAnswers(t *testing.T) {
+	tests := []struct {
+		name                            string
+		cipher                          Cipher
+		key, nonce, plaintext, aad, out string
+	}{
+		{
+			// McGrew & Viega, The Galois/Counter Mode of Operation, test case 14
+			name:      "aes-256-gcm zero key",
+			cipher:    AES256GCM,
+			key:       "0000000000000000000000000000000000000000000000000000000000000000",
+			nonce:     "000000000000000000000000",
+			plaintext: "00000000000000000000000000000000",
+			out:       "cea7403d4d606b6e074ec5d3baf39d18d0d1c8a799996bf0265b98b5d48ab919",
+		},
+		{
+			// McGrew & Viega, test case 16
+			name:      "aes-256-gcm with aad",
+			cipher:    AES256GCM,
+			key:       "feffe9928665731c6d6a8f9467308308feffe9928665731c6d6a8f9467308308",
+			nonce:     "cafebabefacedbaddecaf888",
+			plaintext: "d9313225f88406e5a55909c5aff5269a86a7a9531534f7da2e4c303d8a318a721c3c0c95956809532fcf0e2449a6b525b16aedf5aa0de657ba637b39",
+			aad:       "feedfacedeadbeeffeedfacedeadbeefabaddad2",
+			out:       "522dc1f099567d07f47f37a32a84427d643a8cdcbfe5c0c97598a2bd2555d1aa8cb08e48590dbb3da7b08b1056828838c5f61e6393ba7a0abcc9f66276fc6ece0f4e1768cddf8853bb2d551b",
+		},
+		{
+			// draft-irtf-cfrg-xchacha-01, appendix A.3.1
+			name:      "xchacha20-poly1305",
+			cipher:    XChaCha20Poly1305,
+			key:       "808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f",
+			nonce:     "404142434445464748494a4b4c4d4e4f5051525354555657",
+			plaintext: "4c616469657320616e642047656e746c656d656e206f662074686520636c617373206f66202739393a204966204920636f756c64206f6666657220796f75206f6e6c79206f6e652074697020666f7220746865206675747572652c2073756e73637265656e20776f756c642062652069742e",
+			aad:       "50515253c0c1c2c3c4c5c6c7",
+			out:       "bd6d179d3e83d43b9576579493c0e939572a1700252bfaccbed2902c21396cbb731c7f1b0b4aa6440bf3a82f4eda7e39ae64c6708c54c216cb96b72e1213b4522f8c9ba40db5d945b11b69b982c1bb9e3f3fac2bc369488f76b2383565d3fff921f9664c97637da9768812f615c68b13b52ec0875924c1c7987947deafd8780acf49",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aead, err := tt.cipher.AEAD(unhex(t, tt.key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			nonce := unhex(t, tt.nonce)
+			if len(nonce) != tt.cipher.NonceSize() {
+				t.Fatalf("nonce is %d bytes, cipher wants %d", len(nonce), tt.cipher.NonceSize())
+			}
+			plaintext, aad, want := unhex(t, tt.plaintext), unhex(t, tt.aad), unhex(t, tt.out)
+
+			if got := aead.Seal(nil, nonce, plaintext, aad); !bytes.Equal(got, want) {
+				t.Errorf("Seal = %x, want %x", got, want)
+			}
+			got, err := aead.Open(nil, nonce, want, aad)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Errorf("Open = %x, want %x", got, plaintext)
+			}
+		})
+	}
+}
+
+// sealedEnvelope returns an envelope using c, with one password slot and
+// plaintext sealed, and its data key.
+func sealedEnvelope(t *testing.T, c Cipher, plaintext []byte) (*Envelope, []byte) {
+	t.Helper()
+	dataKey, err := NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := NewEnvelope()
+	env.Cipher = c.ID()
+	if err := env.AddSlot(PasswordCredential("pw"), testParams, dataKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Seal(plaintext, dataKey); err != nil {
+		t.Fatal(err)
+	}
+	return env, dataKey
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	plaintext := []byte(`{"entries":[]}`)
+	for _, c := range Ciphers {
+		t.Run(c.String(), func(t *testing.T) {
+			env, dataKey := sealedEnvelope(t, c, plaintext)
+			if len(env.Nonce) != c.NonceSize() {
+				t.Fatalf("nonce is %d bytes, want %d", len(env.Nonce), c.NonceSize())
+			}
+
+			got, err := env.Open(dataKey)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Errorf("Open = %q, want %q", got, plaintext)
+			}
+
+			data, err := env.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary: %v", err)
+			}
+			parsed, err := ParseEnvelope(data)
+			if err != nil {
+				t.Fatalf("ParseEnvelope: %v", err)
+			}
+			if parsed.Cipher != c.ID() {
+				t.Errorf("Cipher = %d, want %d", parsed.Cipher, c.ID())
+			}
+			if !bytes.Equal(parsed.Nonce, env.Nonce) || !bytes.Equal(parsed.Ciphertext, env.Ciphertext) {
+				t.Error("payload changed in the round trip")
+			}
+
+			key, slot, err := parsed.Unwrap(PasswordCredential("pw"))
+			if err != nil {
+				t.Fatalf("Unwrap: %v", err)
+			}
+			if slot != 0 || !bytes.Equal(key, dataKey) {
+				t.Errorf("Unwrap = slot %d key %x, want slot 0 key %x", slot, key, dataKey)
+			}
+			got, err = parsed.Open(key)
+			if err != nil {
+				t.Fatalf("Open after parse: %v", err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Errorf("Open after parse = %q, want %q", got, plaintext)
+			}
+		})
+	}
+}
+
+func TestEnvelopePrefixTampering(t *testing.T) {
+	for _, c := range Ciphers {
+		env, _ := sealedEnvelope(t, c, []byte("secret"))
+		data, err := env.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := range prefixSize {
+			// 0x03 turns one cipher ID into the other
+			for _, flip := range []byte{0x01, 0x03, 0xff} {
+				tampered := bytes.Clone(data)
+				tampered[i] ^= flip
+
+				err := openEnvelope(tampered, PasswordCredential("pw"))
+				if !errors.Is(err, ErrAuthFailed) && !errors.Is(err, ErrBadHeader) {
+					t.Errorf("%s: byte %d ^ %#x: got %v, want ErrAuthFailed or ErrBadHeader", c, i, flip, err)
+				}
+			}
+		}
+	}
+}
+
+// openEnvelope parses data and opens its payload with cred.
+func openEnvelope(data []byte, cred Credential) error {
+	env, err := ParseEnvelope(data)
+	if err != nil {
+		return err
+	}
+	key, _, err := env.Unwrap(cred)
+	if err != nil {
+		return err
+	}
+	_, err = env.Open(key)
+	return err
+}
@@ -0,0 +1,113 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+)
+
+// KeyfileSize is the number of random bytes in a generated keyfile.
+const KeyfileSize = 64
+
+// ErrKeyfileRequired is returned when the vault can only be opened with a
+// keyfile and none was given.
+var ErrKeyfileRequired = errors.New("this vault requires a keyfile")
+
+// Credential is a secret that unlocks key slots of one kind.
+type Credential struct {
+	Kind   SlotKind
+	Secret []byte
+
+	// Keyfile is the digest of a second-factor keyfile mixed into password
+	// credentials. See WithKeyfile.
+	Keyfile []byte
+}
+
+// PasswordCredential returns a credential for password slots.
+func PasswordCredential(password string) Credential {
+	return Credential{Kind: SlotPassword, Secret: []byte(password)}
+}
+
+// KeyfileCredential reads the keyfile at path and returns a credential for
+// keyfile slots, where the keyfile alone unlocks the vault.
+func KeyfileCredential(path string) (Credential, error) {
+	digest, err := ReadKeyfile(path)
+	if err != nil {
+		return Credential{}, err
+	}
+	return Credential{Kind: SlotKeyfile, Secret: digest}, nil
+}
+
+// WithKeyfile returns a copy of a password credential that also requires
+// the keyfile with the given digest. Slots created from it are
+// SlotPasswordKeyfile slots. A nil digest returns c unchanged.
+func (c Credential) WithKeyfile(digest []byte) Credential {
+	if c.Kind == SlotPassword {
+		c.Keyfile = digest
+	}
+	return c
+}
+
+// Wipe zeroes the secret and keyfile digest of c. Call it as soon as the
+// credential has been used.
+func (c Credential) Wipe() {
+	Wipe(c.Secret)
+	Wipe(c.Keyfile)
+}
+
+// slotKind is the kind of slot created for c.
+func (c Credential) slotKind() SlotKind {
+	if c.Kind == SlotPassword && c.Keyfile != nil {
+		return SlotPasswordKeyfile
+	}
+	return c.Kind
+}
+
+// slotSecret is the input to key derivation for c. For password+keyfile it
+// is the password followed by the fixed-size keyfile digest.
+func (c Credential) slotSecret() []byte {
+	if c.slotKind() != SlotPasswordKeyfile {
+		return c.Secret
+	}
+	secret := make([]byte, 0, len(c.Secret)+len(c.Keyfile))
+	return append(append(secret, c.Secret...), c.Keyfile...)
+}
+
+// ReadKeyfile returns the SHA-256 digest of the keyfile at path. Any file
+// can serve as a keyfile; only its digest is used, so its size does not
+// matter.
+func ReadKeyfile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// GenerateKeyfile writes KeyfileSize random bytes to a new file at path.
+// It refuses to overwrite an existing file.
+func GenerateKeyfile(path string) error {
+	data := make([]byte, KeyfileSize)
+	if _, err := io.ReadFull(rand.Reader, data); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0400)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,64 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func TestUnwrapPasswordKeyfile(t *testing.T) {
+	digest := sha256.Sum256([]byte("keyfile"))
+	other := sha256.Sum256([]byte("another keyfile"))
+	cred := PasswordCredential("pw").WithKeyfile(digest[:])
+
+	dataKey, err := NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := NewEnvelope()
+	if err := env.AddSlot(cred, testParams, dataKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Seal([]byte("secret"), dataKey); err != nil {
+		t.Fatal(err)
+	}
+	data, err := env.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := prefixSize + 1 + slotSize + NonceSize + len("secret") + 16; len(data) != want {
+		t.Errorf("envelope is %d bytes, want %d: the slot must not store anything about the keyfile", len(data), want)
+	}
+	if env, err = ParseEnvelope(data); err != nil {
+		t.Fatal(err)
+	}
+	if env.Slots[0].Kind != SlotPasswordKeyfile {
+		t.Fatalf("slot kind = %s, want %s", env.Slots[0].Kind, SlotPasswordKeyfile)
+	}
+
+	key, _, err := env.Unwrap(cred)
+	if err != nil {
+		t.Fatalf("Unwrap: %v", err)
+	}
+	if !bytes.Equal(key, dataKey) {
+		t.Error("Unwrap returned the wrong data key")
+	}
+
+	tests := []struct {
+		name string
+		cred Credential
+		want error
+	}{
+		{"no keyfile", PasswordCredential("pw"), ErrKeyfileRequired},
+		{"wrong keyfile", PasswordCredential("pw").WithKeyfile(other[:]), ErrBadCredentials},
+		{"wrong password", PasswordCredential("nope").WithKeyfile(digest[:]), ErrBadCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := env.Unwrap(tt.cred); !errors.Is(err, tt.want) {
+				t.Errorf("Unwrap = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
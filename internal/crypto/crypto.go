@@ -1,80 +1,128 @@
 package crypto
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"errors"
-	"io"
+	"fmt"
 
 	"golang.org/x/crypto/argon2"
 )
 
 const (
-	SaltSize   = 16
-	NonceSize  = 12
-	KeySize    = 32 // AES-256
-	ArgonTime  = 1
-	ArgonMem   = 64 * 1024
+	SaltSize     = 16
+	NonceSize    = 12
+	KeySize      = 32 // AES-256
+	ArgonTime    = 1
+	ArgonMem     = 64 * 1024
 	ArgonThreads = 4
+	MaxArgonMem  = 4 * 1024 * 1024 // 4 GiB, upper bound accepted from a file header
 )
 
-// DeriveKey derives a 32-byte key from the password and salt using Argon2id.
-func DeriveKey(password string, salt []byte) []byte {
-	return argon2.IDKey([]byte(password), salt, ArgonTime, ArgonMem, ArgonThreads, KeySize)
+// DeriveKey derives a 32-byte key from the secret and salt using Argon2id.
+func DeriveKey(secret []byte, salt []byte, p Params) []byte {
+	return argon2.IDKey(secret, salt, p.Time, p.Memory, p.Threads, KeySize)
 }
 
-// Encrypt encrypts the plaintext using the password.
-// It returns a byte slice containing Salt + Nonce + Ciphertext.
+// Encrypt encrypts the plaintext using the password and DefaultParams.
+// It returns an envelope with a single password slot.
 func Encrypt(plaintext []byte, password string) ([]byte, error) {
-	// 1. Generate Salt
-	salt := make([]byte, SaltSize)
-	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+	return EncryptWithParams(plaintext, password, DefaultParams)
+}
+
+// EncryptWithParams encrypts the plaintext under a fresh data key and wraps
+// that key with one derived from the password using the given parameters.
+func EncryptWithParams(plaintext []byte, password string, params Params) ([]byte, error) {
+	return EncryptWithCipher(plaintext, password, params, DefaultCipher)
+}
+
+// EncryptWithCipher is EncryptWithParams with the payload encrypted by c.
+func EncryptWithCipher(plaintext []byte, password string, params Params, c Cipher) ([]byte, error) {
+	// 1. Generate Data Key
+	dataKey, err := NewDataKey()
+	if err != nil {
 		return nil, err
 	}
+	defer Wipe(dataKey)
 
-	// 2. Derive Key
-	key := DeriveKey(password, salt)
+	// 2. Wrap it in a password slot
+	env := NewEnvelope()
+	env.Cipher = c.ID()
+	if err := env.AddSlot(PasswordCredential(password), params, dataKey); err != nil {
+		return nil, err
+	}
 
-	// 3. Create Cipher
-	block, err := aes.NewCipher(key)
-	if err != nil {
+	// 3. Encrypt the payload
+	if err := env.Seal(plaintext, dataKey); err != nil {
 		return nil, err
 	}
 
-	aesgcm, err := cipher.NewGCM(block)
+	return env.MarshalBinary()
+}
+
+// Decrypt decrypts the data using the password.
+// It accepts every format version, including legacy Salt + Nonce + Ciphertext.
+func Decrypt(data []byte, password string) ([]byte, error) {
+	return DecryptSecret(data, []byte(password))
+}
+
+// DecryptSecret is Decrypt with the password as bytes, so the caller can
+// wipe it afterwards.
+func DecryptSecret(data, password []byte) ([]byte, error) {
+	switch FormatVersion(data) {
+	case VersionLegacy:
+		return decryptLegacy(data, password)
+	case Version1:
+		return decryptV1(data, password)
+	}
+
+	env, err := ParseEnvelope(data)
 	if err != nil {
 		return nil, err
 	}
+	dataKey, _, err := env.Unwrap(Credential{Kind: SlotPassword, Secret: password})
+	if err != nil {
+		return nil, err
+	}
+	defer Wipe(dataKey)
+	return env.Open(dataKey)
+}
 
-	// 4. Generate Nonce
-	nonce := make([]byte, NonceSize)
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+// decryptV1 decrypts a Version1 file: Header + Ciphertext, keyed directly
+// from the password with the parameters in the header.
+func decryptV1(data, password []byte) ([]byte, error) {
+	// 1. Parse Header
+	header, err := ParseHeader(data)
+	if err != nil {
 		return nil, err
 	}
 
-	// 5. Encrypt
-	ciphertext := aesgcm.Seal(nil, nonce, plaintext, nil)
+	// 2. Derive Key with the parameters the vault was written with
+	key := DeriveKey(password, header.Salt, header.KDF)
+	defer Wipe(key)
 
-	// 6. Pack: Salt + Nonce + Ciphertext
-	result := make([]byte, SaltSize+NonceSize+len(ciphertext))
-	copy(result[0:SaltSize], salt)
-	copy(result[SaltSize:SaltSize+NonceSize], nonce)
-	copy(result[SaltSize+NonceSize:], ciphertext)
+	// 3. Create Cipher
+	aesgcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. Decrypt
+	plaintext, err := aesgcm.Open(nil, header.Nonce, data[HeaderSize:], data[:HeaderSize])
+	if err != nil {
+		return nil, fmt.Errorf("%w: wrong password or corrupted data", ErrBadCredentials)
+	}
 
-	return result, nil
+	return plaintext, nil
 }
 
-// Decrypt decrypts the data using the password.
-// It expects data to be Salt + Nonce + Ciphertext.
-func Decrypt(data []byte, password string) ([]byte, error) {
+// decryptLegacy decrypts vaults written before the header was introduced.
+// They are Salt + Nonce + Ciphertext, keyed with LegacyParams.
+func decryptLegacy(data, password []byte) ([]byte, error) {
 	if len(data) < SaltSize+NonceSize {
-		return nil, errors.New("invalid data length")
+		return nil, fmt.Errorf("%w: truncated", ErrBadHeader)
 	}
 
 	// 1. Extract Salt
 	salt := data[:SaltSize]
-	
+
 	// 2. Extract Nonce
 	nonce := data[SaltSize : SaltSize+NonceSize]
 
@@ -82,15 +130,11 @@ func Decrypt(data []byte, password string) ([]byte, error) {
 	ciphertext := data[SaltSize+NonceSize:]
 
 	// 4. Derive Key
-	key := DeriveKey(password, salt)
+	key := DeriveKey(password, salt, LegacyParams)
+	defer Wipe(key)
 
 	// 5. Create Cipher
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, err
-	}
-
-	aesgcm, err := cipher.NewGCM(block)
+	aesgcm, err := newGCM(key)
 	if err != nil {
 		return nil, err
 	}
@@ -98,7 +142,7 @@ func Decrypt(data []byte, password string) ([]byte, error) {
 	// 6. Decrypt
 	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
-		return nil, errors.New("decryption failed: invalid password or corrupted data")
+		return nil, fmt.Errorf("%w: wrong password or corrupted data", ErrBadCredentials)
 	}
 
 	return plaintext, nil
@@ -0,0 +1,369 @@
+package crypto
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// SlotKind identifies what kind of secret unlocks a key slot.
+type SlotKind uint8
+
+const (
+	// SlotPassword is unlocked by the master password.
+	SlotPassword SlotKind = 1
+
+	// SlotKeyfile is unlocked by the SHA-256 digest of a keyfile.
+	SlotKeyfile SlotKind = 2
+
+	// SlotRecovery is unlocked by a generated recovery key.
+	SlotRecovery SlotKind = 3
+
+	// SlotPasswordKeyfile is unlocked by the master password together with
+	// a keyfile. It is chosen for password credentials that carry a keyfile.
+	SlotPasswordKeyfile SlotKind = 4
+)
+
+func (k SlotKind) String() string {
+	switch k {
+	case SlotPassword:
+		return "password"
+	case SlotKeyfile:
+		return "keyfile"
+	case SlotRecovery:
+		return "recovery key"
+	case SlotPasswordKeyfile:
+		return "password+keyfile"
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(k))
+}
+
+// MaxSlots is the largest number of key slots a vault may hold.
+const MaxSlots = 16
+
+// WrappedKeySize is the size of a data key sealed with AES-GCM.
+const WrappedKeySize = KeySize + 16
+
+// prefixSize is Magic(4) + Version(1) + Cipher(1).
+const prefixSize = len(Magic) + 2
+
+// slotDescSize is Kind(1) + Time(4) + Memory(4) + Threads(1) + Salt.
+const slotDescSize = 1 + 4 + 4 + 1 + SaltSize
+
+// slotSize is the encoded size of a slot: descriptor + Nonce + WrappedKey.
+const slotSize = slotDescSize + NonceSize + WrappedKeySize
+
+// Slot holds the vault data key wrapped by a key derived from one secret.
+type Slot struct {
+	Kind    SlotKind
+	KDF     Params
+	Salt    []byte
+	Nonce   []byte
+	Wrapped []byte
+}
+
+// Envelope is a Version2 vault file. A random data key encrypts the
+// payload; each slot stores that data key wrapped by a key derived from a
+// secret, so secrets can change without re-encrypting the payload.
+//
+// Layout:
+//
+//	Magic(4) Version(1) Cipher(1) SlotCount(1) Slot... Nonce Ciphertext
+//
+// Cipher selects the AEAD for the payload and so the size of its Nonce.
+// Wrapped keys always use AES-256-GCM: each is sealed once under its own
+// derived key, so nonce reuse is not a concern there.
+//
+// The payload is sealed with the prefix (Magic, Version, Cipher) as
+// associated data. Each wrapped key is sealed with its slot descriptor (see slotAAD),
+// so slots can be added or removed without touching the payload.
+type Envelope struct {
+	Cipher     uint8
+	Slots      []Slot
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// NewEnvelope returns an empty envelope using DefaultCipher.
+func NewEnvelope() *Envelope {
+	return &Envelope{Cipher: DefaultCipher.ID()}
+}
+
+// NewDataKey generates a random 256-bit data key.
+func NewDataKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (e *Envelope) prefix() []byte {
+	return append([]byte(Magic), Version2, e.Cipher)
+}
+
+// slotAAD binds a wrapped key to its descriptor. Its prefix names the
+// AES-GCM wrap cipher rather than e.Cipher, so the payload cipher can be
+// changed without rewrapping every slot.
+func (e *Envelope) slotAAD(s Slot) []byte {
+	aad := append([]byte(Magic), Version2, CipherAES256GCM)
+	aad = append(aad, byte(s.Kind))
+	aad = binary.BigEndian.AppendUint32(aad, s.KDF.Time)
+	aad = binary.BigEndian.AppendUint32(aad, s.KDF.Memory)
+	aad = append(aad, s.KDF.Threads)
+	return append(aad, s.Salt...)
+}
+
+// newSlot wraps dataKey with a key derived from cred.
+func (e *Envelope) newSlot(cred Credential, params Params, dataKey []byte) (Slot, error) {
+	if err := params.Validate(); err != nil {
+		return Slot{}, err
+	}
+
+	s := Slot{
+		Kind:  cred.slotKind(),
+		KDF:   params,
+		Salt:  make([]byte, SaltSize),
+		Nonce: make([]byte, NonceSize),
+	}
+	if _, err := io.ReadFull(rand.Reader, s.Salt); err != nil {
+		return Slot{}, err
+	}
+	if _, err := io.ReadFull(rand.Reader, s.Nonce); err != nil {
+		return Slot{}, err
+	}
+	secret := cred.slotSecret()
+	if s.Kind == SlotPasswordKeyfile {
+		defer Wipe(secret)
+	}
+	aead, err := slotAEAD(secret, s.Salt, params)
+	if err != nil {
+		return Slot{}, err
+	}
+	s.Wrapped = aead.Seal(nil, s.Nonce, dataKey, e.slotAAD(s))
+	return s, nil
+}
+
+// slotAEAD derives the key-wrapping key for a slot and returns its AEAD.
+// The derived key is wiped once the cipher holds it.
+func slotAEAD(secret, salt []byte, params Params) (cipher.AEAD, error) {
+	kek := DeriveKey(secret, salt, params)
+	defer Wipe(kek)
+	return newGCM(kek)
+}
+
+// AddSlot appends a slot that unlocks dataKey with cred.
+func (e *Envelope) AddSlot(cred Credential, params Params, dataKey []byte) error {
+	if len(e.Slots) >= MaxSlots {
+		return fmt.Errorf("vault already has the maximum of %d key slots", MaxSlots)
+	}
+	s, err := e.newSlot(cred, params, dataKey)
+	if err != nil {
+		return err
+	}
+	e.Slots = append(e.Slots, s)
+	return nil
+}
+
+// ReplaceSlot rewraps dataKey into slot i for cred with new parameters.
+func (e *Envelope) ReplaceSlot(i int, cred Credential, params Params, dataKey []byte) error {
+	if i < 0 || i >= len(e.Slots) {
+		return fmt.Errorf("no key slot %d", i)
+	}
+	s, err := e.newSlot(cred, params, dataKey)
+	if err != nil {
+		return err
+	}
+	e.Slots[i] = s
+	return nil
+}
+
+// RemoveSlot deletes slot i. The last remaining slot cannot be removed,
+// since the vault would become impossible to open.
+func (e *Envelope) RemoveSlot(i int) error {
+	if i < 0 || i >= len(e.Slots) {
+		return fmt.Errorf("no key slot %d", i)
+	}
+	if len(e.Slots) == 1 {
+		return errors.New("cannot remove the only key slot")
+	}
+	e.Slots = append(e.Slots[:i:i], e.Slots[i+1:]...)
+	return nil
+}
+
+// Unwrap tries every slot cred can open and returns the data key and the
+// index of the slot that opened. Password credentials also try
+// password+keyfile slots; when only those exist and no keyfile was given,
+// the error is ErrKeyfileRequired. A wrong keyfile cannot be told from a
+// wrong password: the keyfile only enters key derivation, so nothing in the
+// file can be checked against it without the password.
+func (e *Envelope) Unwrap(cred Credential) ([]byte, int, error) {
+	keyfileRequired, tried := false, false
+
+	for i, s := range e.Slots {
+		secret := cred.Secret
+		switch {
+		case s.Kind == cred.Kind:
+		case s.Kind == SlotPasswordKeyfile && cred.Kind == SlotPassword:
+			if cred.Keyfile == nil {
+				keyfileRequired = true
+				continue
+			}
+			secret = cred.slotSecret()
+		default:
+			continue
+		}
+
+		tried = true
+		aead, err := slotAEAD(secret, s.Salt, s.KDF)
+		if s.Kind == SlotPasswordKeyfile {
+			Wipe(secret)
+		}
+		if err != nil {
+			return nil, -1, err
+		}
+		if key, err := aead.Open(nil, s.Nonce, s.Wrapped, e.slotAAD(s)); err == nil {
+			return key, i, nil
+		}
+	}
+
+	if !tried && keyfileRequired {
+		return nil, -1, ErrKeyfileRequired
+	}
+	what := cred.Kind.String()
+	if cred.slotKind() == SlotPasswordKeyfile {
+		what = "password or keyfile"
+	}
+	return nil, -1, fmt.Errorf("%w: wrong %s", ErrBadCredentials, what)
+}
+
+// Seal encrypts plaintext with dataKey under a fresh nonce, using the cipher
+// named by e.Cipher.
+func (e *Envelope) Seal(plaintext, dataKey []byte) error {
+	c, err := CipherByID(e.Cipher)
+	if err != nil {
+		return err
+	}
+	aead, err := c.AEAD(dataKey)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, c.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	e.Nonce = nonce
+	e.Ciphertext = aead.Seal(nil, nonce, plaintext, e.prefix())
+	return nil
+}
+
+// Open decrypts the payload with dataKey.
+func (e *Envelope) Open(dataKey []byte) ([]byte, error) {
+	c, err := CipherByID(e.Cipher)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := c.AEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, e.Nonce, e.Ciphertext, e.prefix())
+	if err != nil {
+		return nil, ErrAuthFailed
+	}
+	return plaintext, nil
+}
+
+// MarshalBinary encodes the envelope in its on-disk form.
+func (e *Envelope) MarshalBinary() ([]byte, error) {
+	if len(e.Slots) == 0 {
+		return nil, errors.New("vault has no key slots")
+	}
+	if len(e.Slots) > MaxSlots {
+		return nil, fmt.Errorf("vault has more than %d key slots", MaxSlots)
+	}
+	c, err := CipherByID(e.Cipher)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.Nonce) != c.NonceSize() {
+		return nil, errors.New("vault payload is not sealed")
+	}
+
+	buf := make([]byte, 0, prefixSize+1+len(e.Slots)*slotSize+len(e.Nonce)+len(e.Ciphertext))
+	buf = append(buf, e.prefix()...)
+	buf = append(buf, byte(len(e.Slots)))
+	for _, s := range e.Slots {
+		if len(s.Salt) != SaltSize || len(s.Nonce) != NonceSize || len(s.Wrapped) != WrappedKeySize {
+			return nil, errors.New("invalid key slot")
+		}
+		buf = append(buf, byte(s.Kind))
+		buf = binary.BigEndian.AppendUint32(buf, s.KDF.Time)
+		buf = binary.BigEndian.AppendUint32(buf, s.KDF.Memory)
+		buf = append(buf, s.KDF.Threads)
+		buf = append(buf, s.Salt...)
+		buf = append(buf, s.Nonce...)
+		buf = append(buf, s.Wrapped...)
+	}
+	buf = append(buf, e.Nonce...)
+	buf = append(buf, e.Ciphertext...)
+	return buf, nil
+}
+
+// ParseEnvelope decodes a Version2 vault file.
+func ParseEnvelope(data []byte) (*Envelope, error) {
+	if !HasMagic(data) {
+		return nil, fmt.Errorf("%w: missing magic bytes", ErrBadHeader)
+	}
+	if len(data) < prefixSize+1 {
+		return nil, fmt.Errorf("%w: truncated", ErrBadHeader)
+	}
+	if v := FormatVersion(data); v != Version2 {
+		return nil, fmt.Errorf("%w: unsupported format version %d", ErrBadHeader, v)
+	}
+
+	e := &Envelope{Cipher: data[len(Magic)+1]}
+	c, err := CipherByID(e.Cipher)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBadHeader, err)
+	}
+
+	count := int(data[prefixSize])
+	if count == 0 || count > MaxSlots {
+		return nil, fmt.Errorf("%w: %d key slots", ErrBadHeader, count)
+	}
+
+	p := prefixSize + 1
+	e.Slots = make([]Slot, count)
+	for i := range e.Slots {
+		if len(data) < p+slotSize {
+			return nil, fmt.Errorf("%w: truncated", ErrBadHeader)
+		}
+		s := Slot{Kind: SlotKind(data[p])}
+		s.KDF.Time = binary.BigEndian.Uint32(data[p+1:])
+		s.KDF.Memory = binary.BigEndian.Uint32(data[p+5:])
+		s.KDF.Threads = data[p+9]
+		if err := s.KDF.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: key slot %d: %w", ErrBadHeader, i, err)
+		}
+		p += 10
+		s.Salt = data[p : p+SaltSize]
+		p += SaltSize
+		s.Nonce = data[p : p+NonceSize]
+		p += NonceSize
+		s.Wrapped = data[p : p+WrappedKeySize]
+		p += WrappedKeySize
+		e.Slots[i] = s
+	}
+
+	n := c.NonceSize()
+	if len(data) < p+n {
+		return nil, fmt.Errorf("%w: truncated", ErrBadHeader)
+	}
+	e.Nonce = data[p : p+n]
+	e.Ciphertext = data[p+n:]
+	return e, nil
+}
@@ -0,0 +1,20 @@
+package crypto
+
+import "errors"
+
+// Errors returned when a vault cannot be opened. They are wrapped with
+// details, so test for them with errors.Is.
+var (
+	// ErrBadCredentials means the secret did not open any key slot: a wrong
+	// password, keyfile or recovery key. The vault itself may be fine. For
+	// vaults older than the envelope format a damaged file looks the same.
+	ErrBadCredentials = errors.New("unlock failed")
+
+	// ErrAuthFailed means the data key was recovered but the payload failed
+	// its authentication tag: the file was damaged or modified.
+	ErrAuthFailed = errors.New("vault data failed authentication")
+
+	// ErrBadHeader means the file is not a readable vault: missing magic,
+	// truncated, or an unsupported version or cipher.
+	ErrBadHeader = errors.New("invalid vault header")
+)
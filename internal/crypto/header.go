@@ -0,0 +1,176 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Magic identifies a compass vault file. Files without it are treated as the
+// legacy headerless format (Salt + Nonce + Ciphertext).
+const Magic = "ACMP"
+
+const (
+	// VersionLegacy is reported for headerless files.
+	VersionLegacy uint8 = 0
+
+	// Version1 is the first self-describing format: header followed by the
+	// AES-GCM ciphertext, with the header bound as associated data.
+	Version1 uint8 = 1
+
+	// Version2 is the envelope format: a random data key encrypts the
+	// payload and is stored wrapped by password-derived keys in slots.
+	Version2 uint8 = 2
+
+	// CurrentVersion is the format written by Encrypt.
+	CurrentVersion = Version2
+)
+
+const (
+	// CipherAES256GCM is AES-256 in Galois/Counter Mode.
+	CipherAES256GCM uint8 = 1
+
+	// CipherXChaCha20Poly1305 is XChaCha20-Poly1305 with 24-byte nonces.
+	// Envelope payloads only; key slots are always wrapped with AES-GCM.
+	CipherXChaCha20Poly1305 uint8 = 2
+)
+
+// HeaderSize is the encoded size of a Version1 header:
+// Magic(4) + Version(1) + Cipher(1) + Time(4) + Memory(4) + Threads(1) + Salt + Nonce.
+const HeaderSize = len(Magic) + 1 + 1 + 4 + 4 + 1 + SaltSize + NonceSize
+
+// Params holds the Argon2id cost parameters used to derive a key.
+type Params struct {
+	Time    uint32 // Iterations
+	Memory  uint32 // KiB
+	Threads uint8
+}
+
+// LegacyParams are the fixed parameters headerless vaults were written with.
+var LegacyParams = Params{
+	Time:    ArgonTime,
+	Memory:  ArgonMem,
+	Threads: ArgonThreads,
+}
+
+// DefaultParams are the parameters used for new vaults.
+var DefaultParams = LegacyParams
+
+// Validate rejects parameters that would make key derivation meaningless or
+// could be used to exhaust memory when read from an untrusted file.
+func (p Params) Validate() error {
+	if p.Time == 0 || p.Memory == 0 || p.Threads == 0 {
+		return errors.New("invalid key derivation parameters")
+	}
+	if p.Memory < 8*uint32(p.Threads) {
+		return errors.New("argon2 memory must be at least 8 KiB per thread")
+	}
+	if p.Memory > MaxArgonMem {
+		return fmt.Errorf("argon2 memory %d KiB exceeds limit of %d KiB", p.Memory, MaxArgonMem)
+	}
+	return nil
+}
+
+func (p Params) String() string {
+	return fmt.Sprintf("t=%d m=%dKiB p=%d", p.Time, p.Memory, p.Threads)
+}
+
+// Header is the plaintext prefix of a Version1 vault file. It records
+// everything needed to derive the key and decrypt the payload, and is
+// authenticated as AEAD associated data so it cannot be altered without
+// detection.
+type Header struct {
+	Version uint8
+	Cipher  uint8
+	KDF     Params
+	Salt    []byte
+	Nonce   []byte
+}
+
+// HasMagic reports whether data starts with the vault magic bytes.
+func HasMagic(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(Magic))
+}
+
+// FormatVersion returns the format version of a vault file, or
+// VersionLegacy if it has no header.
+func FormatVersion(data []byte) uint8 {
+	if !HasMagic(data) || len(data) <= len(Magic) {
+		return VersionLegacy
+	}
+	return data[len(Magic)]
+}
+
+// MarshalBinary encodes the header in its on-disk form.
+func (h Header) MarshalBinary() ([]byte, error) {
+	if len(h.Salt) != SaltSize || len(h.Nonce) != NonceSize {
+		return nil, errors.New("invalid header: bad salt or nonce length")
+	}
+
+	buf := make([]byte, 0, HeaderSize)
+	buf = append(buf, Magic...)
+	buf = append(buf, h.Version, h.Cipher)
+	buf = binary.BigEndian.AppendUint32(buf, h.KDF.Time)
+	buf = binary.BigEndian.AppendUint32(buf, h.KDF.Memory)
+	buf = append(buf, h.KDF.Threads)
+	buf = append(buf, h.Salt...)
+	buf = append(buf, h.Nonce...)
+	return buf, nil
+}
+
+// ParseHeader decodes the Version1 header at the start of data.
+func ParseHeader(data []byte) (Header, error) {
+	var h Header
+	if !HasMagic(data) {
+		return h, fmt.Errorf("%w: missing magic bytes", ErrBadHeader)
+	}
+	if len(data) < HeaderSize {
+		return h, fmt.Errorf("%w: truncated", ErrBadHeader)
+	}
+
+	p := len(Magic)
+	h.Version = data[p]
+	h.Cipher = data[p+1]
+	p += 2
+
+	if h.Version != Version1 {
+		return h, fmt.Errorf("%w: unsupported format version %d", ErrBadHeader, h.Version)
+	}
+	if h.Cipher != CipherAES256GCM {
+		return h, fmt.Errorf("%w: unsupported cipher id %d", ErrBadHeader, h.Cipher)
+	}
+
+	h.KDF.Time = binary.BigEndian.Uint32(data[p:])
+	h.KDF.Memory = binary.BigEndian.Uint32(data[p+4:])
+	h.KDF.Threads = data[p+8]
+	p += 9
+	if err := h.KDF.Validate(); err != nil {
+		return h, fmt.Errorf("%w: %w", ErrBadHeader, err)
+	}
+
+	h.Salt = data[p : p+SaltSize]
+	p += SaltSize
+	h.Nonce = data[p : p+NonceSize]
+
+	return h, nil
+}
+
+// Below reports whether p is weaker than min in time or memory cost.
+func (p Params) Below(min Params) bool {
+	return p.Time < min.Time || p.Memory < min.Memory
+}
+
+// Raise returns p with each cost raised to at least the value in min.
+func (p Params) Raise(min Params) Params {
+	if p.Time < min.Time {
+		p.Time = min.Time
+	}
+	if p.Memory < min.Memory {
+		p.Memory = min.Memory
+	}
+	if p.Threads < min.Threads {
+		p.Threads = min.Threads
+	}
+	return p
+}
@@ -0,0 +1,94 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RecoveryKeySize is the entropy of a recovery key in bytes (128 bits).
+const RecoveryKeySize = 16
+
+// recoveryChecksumSize is the number of SHA-256 bytes appended to the key
+// before it is turned into words, so typos are caught before unlocking.
+const recoveryChecksumSize = 2
+
+// RecoveryWords is the number of words in a recovery mnemonic.
+const RecoveryWords = RecoveryKeySize + recoveryChecksumSize
+
+//go:embed wordlist.txt
+var wordlistData string
+
+// wordlist holds 256 words, one per byte value. Every word is unique in its
+// first four letters, so a mnemonic may be typed with abbreviated words.
+var wordlist = strings.Fields(wordlistData)
+
+var wordIndex = func() map[string]byte {
+	idx := make(map[string]byte, len(wordlist)*2)
+	for i, w := range wordlist {
+		idx[w] = byte(i)
+		if len(w) > 4 {
+			idx[w[:4]] = byte(i)
+		}
+	}
+	return idx
+}()
+
+// NewRecoveryKey generates a random recovery key.
+func NewRecoveryKey() ([]byte, error) {
+	key := make([]byte, RecoveryKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// RecoveryCredential returns a credential for recovery key slots.
+func RecoveryCredential(key []byte) Credential {
+	return Credential{Kind: SlotRecovery, Secret: key}
+}
+
+// EncodeMnemonic turns a recovery key into words, one per byte, followed by
+// checksum words.
+func EncodeMnemonic(key []byte) []string {
+	sum := sha256.Sum256(key)
+	data := append(append([]byte{}, key...), sum[:recoveryChecksumSize]...)
+
+	words := make([]string, len(data))
+	for i, b := range data {
+		words[i] = wordlist[b]
+	}
+	return words
+}
+
+// DecodeMnemonic parses a recovery mnemonic and verifies its checksum.
+// Words may be separated by any whitespace, in any case, and abbreviated
+// to their first four letters.
+func DecodeMnemonic(mnemonic string) ([]byte, error) {
+	words := strings.Fields(strings.ToLower(mnemonic))
+	if len(words) != RecoveryWords {
+		return nil, fmt.Errorf("recovery key must be %d words, got %d", RecoveryWords, len(words))
+	}
+
+	data := make([]byte, len(words))
+	for i, w := range words {
+		b, ok := wordIndex[w]
+		if !ok {
+			return nil, fmt.Errorf("word %d (%q) is not in the recovery word list", i+1, w)
+		}
+		data[i] = b
+	}
+
+	key := data[:RecoveryKeySize]
+	sum := sha256.Sum256(key)
+	for i := 0; i < recoveryChecksumSize; i++ {
+		if data[RecoveryKeySize+i] != sum[i] {
+			return nil, errors.New("recovery key checksum mismatch: check the words for typos")
+		}
+	}
+	return key, nil
+}
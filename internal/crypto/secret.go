@@ -0,0 +1,62 @@
+package crypto
+
+import "errors"
+
+// SecretBuffer holds key material outside the Go heap where the OS allows:
+// the memory is locked so it is never swapped to disk, and it is zeroed on
+// Destroy. Where locking is unavailable it falls back to an ordinary slice
+// that is still zeroed on Destroy.
+type SecretBuffer struct {
+	data   []byte
+	locked bool
+	free   func([]byte)
+}
+
+// NewSecretBuffer allocates a zeroed buffer of size bytes.
+func NewSecretBuffer(size int) (*SecretBuffer, error) {
+	if size <= 0 {
+		return nil, errors.New("secret buffer size must be positive")
+	}
+	return allocSecret(size), nil
+}
+
+// SecretBufferFrom moves b into a new SecretBuffer and wipes b.
+func SecretBufferFrom(b []byte) (*SecretBuffer, error) {
+	s, err := NewSecretBuffer(len(b))
+	if err != nil {
+		return nil, err
+	}
+	copy(s.data, b)
+	Wipe(b)
+	return s, nil
+}
+
+// Bytes returns the secret. The slice is only valid until Destroy and must
+// not be retained.
+func (s *SecretBuffer) Bytes() []byte {
+	return s.data
+}
+
+// Locked reports whether the buffer is locked in RAM.
+func (s *SecretBuffer) Locked() bool {
+	return s.locked
+}
+
+// Destroy zeroes and releases the buffer. It is safe to call more than
+// once and on a nil buffer.
+func (s *SecretBuffer) Destroy() {
+	if s == nil || s.data == nil {
+		return
+	}
+	Wipe(s.data)
+	if s.free != nil {
+		s.free(s.data)
+	}
+	s.data = nil
+	s.locked = false
+}
+
+// Wipe overwrites b with zeros.
+func Wipe(b []byte) {
+	clear(b)
+}
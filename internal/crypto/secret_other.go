@@ -0,0 +1,9 @@
+//go:build !unix
+
+package crypto
+
+// allocSecret returns an ordinary slice on systems without mlock. It is
+// still zeroed on Destroy.
+func allocSecret(size int) *SecretBuffer {
+	return &SecretBuffer{data: make([]byte, size)}
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package crypto
+
+import "golang.org/x/sys/unix"
+
+// allocSecret maps anonymous memory for the secret and locks it. If the
+// mapping fails, or locking is refused (e.g. RLIMIT_MEMLOCK), the buffer is
+// still usable but may be swapped.
+func allocSecret(size int) *SecretBuffer {
+	data, err := unix.Mmap(-1, 0, size, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_ANON|unix.MAP_PRIVATE)
+	if err != nil {
+		return &SecretBuffer{data: make([]byte, size)}
+	}
+
+	s := &SecretBuffer{
+		data: data,
+		free: func(b []byte) {
+			unix.Munlock(b)
+			unix.Munmap(b)
+		},
+	}
+	s.locked = unix.Mlock(data) == nil
+	return s
+}
@@ -0,0 +1,229 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ShareVersion is the encoding version of a Shamir share.
+const ShareVersion uint8 = 1
+
+// SharePrefix starts every encoded share so it is recognisable on paper.
+const SharePrefix = "compass-share-"
+
+// shareChecksumSize is the number of SHA-256 bytes appended to each share.
+const shareChecksumSize = 4
+
+var shareEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Share is one piece of a secret split with Shamir's scheme. Any Threshold
+// shares with the same SetID reconstruct the secret; fewer reveal nothing.
+type Share struct {
+	SetID     [2]byte // Random tag shared by all pieces of one split
+	Threshold uint8
+	X         uint8 // Evaluation point, never zero
+	Data      []byte
+}
+
+// SplitSecret splits secret into n shares so that any k reconstruct it.
+func SplitSecret(secret []byte, n, k int) ([]Share, error) {
+	if k < 2 || k > n || n > 255 {
+		return nil, fmt.Errorf("invalid share parameters: need 2 <= threshold <= shares <= 255, got %d of %d", k, n)
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("cannot split an empty secret")
+	}
+
+	var setID [2]byte
+	if _, err := io.ReadFull(rand.Reader, setID[:]); err != nil {
+		return nil, err
+	}
+
+	shares := make([]Share, n)
+	for i := range shares {
+		shares[i] = Share{SetID: setID, Threshold: uint8(k), X: uint8(i + 1), Data: make([]byte, len(secret))}
+	}
+
+	// One random polynomial of degree k-1 per secret byte, with the byte
+	// as its constant term.
+	coeffs := make([]byte, k)
+	for j, b := range secret {
+		coeffs[0] = b
+		if _, err := io.ReadFull(rand.Reader, coeffs[1:]); err != nil {
+			return nil, err
+		}
+		for i := range shares {
+			shares[i].Data[j] = gfEval(coeffs, shares[i].X)
+		}
+	}
+	for i := range coeffs {
+		coeffs[i] = 0
+	}
+
+	return shares, nil
+}
+
+// CombineShares reconstructs the secret from at least Threshold shares.
+func CombineShares(shares []Share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("no shares given")
+	}
+
+	first := shares[0]
+	if first.Threshold < 2 {
+		return nil, fmt.Errorf("invalid share threshold %d", first.Threshold)
+	}
+	seen := make(map[uint8]bool, len(shares))
+	for _, s := range shares {
+		if s.SetID != first.SetID {
+			return nil, errors.New("shares come from different splits")
+		}
+		if s.Threshold != first.Threshold || len(s.Data) != len(first.Data) {
+			return nil, errors.New("shares are inconsistent")
+		}
+		if s.X == 0 {
+			return nil, errors.New("invalid share index 0")
+		}
+		if seen[s.X] {
+			return nil, fmt.Errorf("share %d given more than once", s.X)
+		}
+		seen[s.X] = true
+	}
+	if len(shares) < int(first.Threshold) {
+		return nil, fmt.Errorf("need %d shares, got %d", first.Threshold, len(shares))
+	}
+
+	use := shares[:first.Threshold]
+	secret := make([]byte, len(first.Data))
+	for j := range secret {
+		// Lagrange interpolation at x = 0
+		var acc byte
+		for i, si := range use {
+			num, den := byte(1), byte(1)
+			for m, sm := range use {
+				if m == i {
+					continue
+				}
+				num = gfMul(num, sm.X)
+				den = gfMul(den, si.X^sm.X)
+			}
+			acc ^= gfMul(si.Data[j], gfDiv(num, den))
+		}
+		secret[j] = acc
+	}
+	return secret, nil
+}
+
+// String encodes the share as printable text:
+// compass-share-<threshold>-<x>-<base32 payload and checksum, in groups of 4>.
+func (s Share) String() string {
+	payload := s.payload()
+	sum := sha256.Sum256(payload)
+	enc := shareEncoding.EncodeToString(append(payload, sum[:shareChecksumSize]...))
+
+	groups := make([]string, 0, len(enc)/4+1)
+	for len(enc) > 4 {
+		groups = append(groups, enc[:4])
+		enc = enc[4:]
+	}
+	groups = append(groups, enc)
+
+	return fmt.Sprintf("%s%d-%d-%s", SharePrefix, s.Threshold, s.X, strings.Join(groups, "-"))
+}
+
+// payload is Version(1) SetID(2) Threshold(1) X(1) Data.
+func (s Share) payload() []byte {
+	p := []byte{ShareVersion, s.SetID[0], s.SetID[1], s.Threshold, s.X}
+	return append(p, s.Data...)
+}
+
+// ParseShare decodes a share produced by Share.String and verifies its
+// checksum. Case and surrounding whitespace are ignored.
+func ParseShare(text string) (Share, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if !strings.HasPrefix(text, SharePrefix) {
+		return Share{}, errors.New("not a compass share")
+	}
+
+	parts := strings.Split(strings.TrimPrefix(text, SharePrefix), "-")
+	if len(parts) < 3 {
+		return Share{}, errors.New("share is truncated")
+	}
+	raw, err := shareEncoding.DecodeString(strings.ToUpper(strings.Join(parts[2:], "")))
+	if err != nil {
+		return Share{}, fmt.Errorf("share is not valid base32: %w", err)
+	}
+	if len(raw) < 5+1+shareChecksumSize {
+		return Share{}, errors.New("share is truncated")
+	}
+
+	payload, checksum := raw[:len(raw)-shareChecksumSize], raw[len(raw)-shareChecksumSize:]
+	sum := sha256.Sum256(payload)
+	if !bytes.Equal(sum[:shareChecksumSize], checksum) {
+		return Share{}, errors.New("share checksum mismatch: check it for typos")
+	}
+	if payload[0] != ShareVersion {
+		return Share{}, fmt.Errorf("unsupported share version %d", payload[0])
+	}
+
+	s := Share{
+		SetID:     [2]byte{payload[1], payload[2]},
+		Threshold: payload[3],
+		X:         payload[4],
+		Data:      payload[5:],
+	}
+	if parts[0] != fmt.Sprint(s.Threshold) || parts[1] != fmt.Sprint(s.X) {
+		return Share{}, errors.New("share label does not match its contents")
+	}
+	// SplitSecret never writes these; they would reveal the secret or
+	// combine to garbage
+	if s.Threshold < 2 {
+		return Share{}, fmt.Errorf("invalid share threshold %d", s.Threshold)
+	}
+	if s.X == 0 {
+		return Share{}, errors.New("invalid share index 0")
+	}
+	return s, nil
+}
+
+// GF(2^8) arithmetic with the AES reduction polynomial x^8+x^4+x^3+x+1.
+
+// gfMul multiplies without data-dependent branches, since its inputs are
+// secret bytes.
+func gfMul(a, b byte) byte {
+	var p byte
+	for i := 0; i < 8; i++ {
+		p ^= -(b & 1) & a
+		a = (a << 1) ^ (-(a >> 7) & 0x1b)
+		b >>= 1
+	}
+	return p
+}
+
+// gfInv returns the multiplicative inverse of a (a^254), a must not be zero.
+func gfInv(a byte) byte {
+	result := byte(1)
+	for i := 0; i < 254; i++ {
+		result = gfMul(result, a)
+	}
+	return result
+}
+
+func gfDiv(a, b byte) byte {
+	return gfMul(a, gfInv(b))
+}
+
+// gfEval evaluates the polynomial with the given coefficients at x.
+func gfEval(coeffs []byte, x byte) byte {
+	var y byte
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ coeffs[i]
+	}
+	return y
+}
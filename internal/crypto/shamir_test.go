@@ -0,0 +1,98 @@
+package crypto
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSplitCombine(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	shares, err := SplitSecret(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every set of three shares, in any order, and more than three
+	sets := [][]int{{0, 1, 2}, {0, 1, 3}, {0, 2, 4}, {1, 3, 4}, {4, 2, 0}, {2, 3, 4}, {0, 1, 2, 3}, {0, 1, 2, 3, 4}}
+	for _, set := range sets {
+		var use []Share
+		for _, i := range set {
+			// Through the text form, as the shares are used in practice
+			s, err := ParseShare(shares[i].String())
+			if err != nil {
+				t.Fatalf("ParseShare(share %d): %v", i, err)
+			}
+			use = append(use, s)
+		}
+		got, err := CombineShares(use)
+		if err != nil {
+			t.Fatalf("CombineShares(%v): %v", set, err)
+		}
+		if !bytes.Equal(got, secret) {
+			t.Errorf("CombineShares(%v) = %x, want %x", set, got, secret)
+		}
+	}
+
+	if _, err := CombineShares(shares[:2]); err == nil {
+		t.Error("CombineShares accepted fewer shares than the threshold")
+	}
+	if _, err := CombineShares([]Share{shares[0], shares[0], shares[1]}); err == nil {
+		t.Error("CombineShares accepted the same share twice")
+	}
+	other, err := SplitSecret(secret, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CombineShares([]Share{shares[0], other[1], shares[2]}); err == nil {
+		t.Error("CombineShares accepted shares from different splits")
+	}
+}
+
+func TestSplitSecretParameters(t *testing.T) {
+	for _, p := range [][2]int{{3, 1}, {3, 0}, {2, 3}, {256, 2}} {
+		if _, err := SplitSecret([]byte("secret"), p[0], p[1]); err == nil {
+			t.Errorf("SplitSecret(n=%d, k=%d) succeeded", p[0], p[1])
+		}
+	}
+}
+
+func TestMalformedShares(t *testing.T) {
+	data := []byte("0123456789abcdef")
+	tests := []struct {
+		name  string
+		share Share
+	}{
+		{"threshold 0", Share{SetID: [2]byte{1, 2}, Threshold: 0, X: 1, Data: data}},
+		{"threshold 1", Share{SetID: [2]byte{1, 2}, Threshold: 1, X: 1, Data: data}},
+		{"index 0", Share{SetID: [2]byte{1, 2}, Threshold: 2, X: 0, Data: data}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A well-formed encoding with a valid checksum
+			if _, err := ParseShare(tt.share.String()); err == nil {
+				t.Error("ParseShare accepted the share")
+			}
+			if secret, err := CombineShares([]Share{tt.share}); err == nil {
+				t.Errorf("CombineShares = %x, want an error", secret)
+			}
+		})
+	}
+
+	shares, err := SplitSecret(data, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := shares[0].String()
+	i := len(SharePrefix + "2-1-") // First payload character
+	typo := text[:i] + "z" + text[i+1:]
+	if text[i] == 'z' {
+		typo = text[:i] + "y" + text[i+1:]
+	}
+	if _, err := ParseShare(typo); err == nil {
+		t.Error("ParseShare accepted a share with a typo")
+	}
+	if _, err := ParseShare(strings.Replace(text, "-2-1-", "-2-2-", 1)); err == nil {
+		t.Error("ParseShare accepted a label that does not match the contents")
+	}
+}
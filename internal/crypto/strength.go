@@ -0,0 +1,123 @@
+package crypto
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Strength scores from EstimateStrength.
+const (
+	StrengthVeryWeak = iota
+	StrengthWeak
+	StrengthFair
+	StrengthStrong
+	StrengthVeryStrong
+)
+
+// MinStrength is the lowest score accepted for a master password without
+// an explicit override.
+const MinStrength = StrengthFair
+
+// Strength is a rough estimate of how hard a password is to guess.
+type Strength struct {
+	Bits  float64
+	Score int // StrengthVeryWeak to StrengthVeryStrong
+}
+
+func (s Strength) String() string {
+	switch s.Score {
+	case StrengthVeryWeak:
+		return "very weak"
+	case StrengthWeak:
+		return "weak"
+	case StrengthFair:
+		return "fair"
+	case StrengthStrong:
+		return "strong"
+	}
+	return "very strong"
+}
+
+// Weak reports whether s is below MinStrength.
+func (s Strength) Weak() bool {
+	return s.Score < MinStrength
+}
+
+// commonPasswords are rejected outright, also with digits or symbols added
+// around them.
+var commonPasswords = map[string]bool{
+	"password": true, "passwort": true, "qwerty": true, "qwertz": true,
+	"azerty": true, "letmein": true, "welcome": true, "admin": true,
+	"iloveyou": true, "monkey": true, "dragon": true, "master": true,
+	"secret": true, "login": true, "abc": true, "football": true,
+	"baseball": true, "sunshine": true, "princess": true, "shadow": true,
+	"trustno": true, "changeme": true, "compass": true, "atlas": true,
+}
+
+// EstimateStrength estimates the entropy of a password from its length and
+// the character classes it uses. Repeated and sequential characters count
+// for little, and well-known passwords are scored as very weak. It is a
+// guide for people, not a guarantee.
+func EstimateStrength(password string) Strength {
+	var lower, upper, digit, symbol, other bool
+	var effective float64
+	var prev rune = -1
+	for _, r := range password {
+		switch {
+		case r < unicode.MaxASCII && unicode.IsLower(r):
+			lower = true
+		case r < unicode.MaxASCII && unicode.IsUpper(r):
+			upper = true
+		case r < unicode.MaxASCII && unicode.IsDigit(r):
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+
+		if r == prev || r == prev+1 || r == prev-1 {
+			effective += 0.25 // "aaaa", "1234", "cba"
+		} else {
+			effective++
+		}
+		prev = r
+	}
+
+	pool := 0
+	for _, c := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if c.used {
+			pool += c.size
+		}
+	}
+
+	var bits float64
+	if pool > 0 {
+		bits = effective * math.Log2(float64(pool))
+	}
+	core := strings.TrimFunc(strings.ToLower(password), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if commonPasswords[core] {
+		bits = math.Min(bits, 10)
+	}
+
+	s := Strength{Bits: bits}
+	switch {
+	case bits < 28:
+		s.Score = StrengthVeryWeak
+	case bits < 40:
+		s.Score = StrengthWeak
+	case bits < 56:
+		s.Score = StrengthFair
+	case bits < 72:
+		s.Score = StrengthStrong
+	default:
+		s.Score = StrengthVeryStrong
+	}
+	return s
+}
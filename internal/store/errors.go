@@ -0,0 +1,86 @@
+package store
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/fezcode/atlas.compass/internal/crypto"
+	"github.com/fezcode/atlas.compass/pkg/model"
+)
+
+var (
+	// ErrNotFound means there is no vault file yet.
+	ErrNotFound = errors.New("no vault found")
+
+	// ErrCorrupt means the vault decrypted but its contents are not valid
+	// vault JSON.
+	ErrCorrupt = errors.New("vault contents are corrupted")
+
+	// ErrPermission means the vault file or directory could not be read or
+	// written because of its ownership or mode.
+	ErrPermission = errors.New("cannot access vault file")
+
+	// ErrConflict means the vault file changed on disk in ways that clash
+	// with the changes being saved.
+	ErrConflict = errors.New("vault changed on disk")
+)
+
+// ConflictError is returned by Session.Save when the vault file changed on
+// disk and both sides changed the same entries. Nothing was written.
+type ConflictError struct {
+	// Merged holds every change that merged cleanly, and our version of
+	// each conflicting entry
+	Merged    *model.Vault
+	Conflicts []model.Conflict
+
+	// Hash is the SHA-256 of the file the conflicts were found against
+	Hash [sha256.Size]byte
+
+	theirs *model.Vault // The entries of that file
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%v: %d conflicting entries", ErrConflict, len(e.Conflicts))
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// fsError tags permission failures with ErrPermission.
+func fsError(err error) error {
+	if errors.Is(err, fs.ErrPermission) {
+		return fmt.Errorf("%w: %w", ErrPermission, err)
+	}
+	return err
+}
+
+// Hint suggests what to do about an error from this package, or returns ""
+// if there is nothing specific to say.
+func Hint(err error) string {
+	var inUse *InUseError
+	switch {
+	case errors.As(err, &inUse):
+		return fmt.Sprintf("Close the other atlas.compass first; list, get, run and inject still work meanwhile. If it crashed on another machine, delete %s.", inUse.Path)
+	case errors.Is(err, ErrConflict):
+		return "Nothing was saved. The vault file was changed meanwhile, e.g. through a synced folder; run the command again."
+	case errors.Is(err, ErrReadOnly):
+		return "Another atlas.compass holds the vault lock. Close it and unlock again to make changes."
+	case errors.Is(err, ErrNotFound):
+		if Name() != DefaultName {
+			return fmt.Sprintf("Run atlas.compass -vault %s without a command to create this vault.", Name())
+		}
+		return "Run atlas.compass without a command to create your vault."
+	case errors.Is(err, crypto.ErrKeyfileRequired):
+		return "Pass --keyfile <path>, or set the keyfile for this vault in ~/.atlas/compass.json."
+	case errors.Is(err, crypto.ErrBadCredentials):
+		return "Check for typos and Caps Lock. If the password is lost, unlock with a keyfile or recovery key instead."
+	case errors.Is(err, crypto.ErrBadHeader), errors.Is(err, crypto.ErrAuthFailed), errors.Is(err, ErrCorrupt):
+		return "The vault file is damaged; your password is not the problem, so do not change it. Restore the vault file from a backup."
+	case errors.Is(err, ErrPermission):
+		return "Make sure the vault file and its directory belong to you (directory 0700, file 0600)."
+	}
+	return ""
+}
@@ -0,0 +1,169 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	DirName  = ".atlas"
+	FileName = "compass.enc"
+
+	// DefaultName is the name of the vault stored as ~/.atlas/compass.enc.
+	DefaultName = "default"
+
+	// Ext is the file extension of named vaults in ~/.atlas.
+	Ext = ".enc"
+)
+
+// ErrInvalidName means a vault name cannot be used as a file name.
+var ErrInvalidName = errors.New("invalid vault name")
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// selected is the vault the package-level functions work on. An empty path
+// means the default vault.
+var selected struct {
+	name, path string
+}
+
+// Dir returns the ~/.atlas directory, which holds the configuration and the
+// named vaults.
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, DirName), nil
+}
+
+// ValidateName reports whether name can be used for a vault in ~/.atlas.
+func ValidateName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("%w %q: use letters, digits, '-' and '_'", ErrInvalidName, name)
+	}
+	if name == strings.TrimSuffix(FileName, Ext) {
+		return fmt.Errorf("%w %q: it is the file of the %s vault", ErrInvalidName, name, DefaultName)
+	}
+	return nil
+}
+
+// VaultPath returns the file of the named vault: ~/.atlas/<name>.enc, or
+// ~/.atlas/compass.enc for the default one.
+func VaultPath(name string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	if name == "" || name == DefaultName {
+		return filepath.Join(dir, FileName), nil
+	}
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+Ext), nil
+}
+
+// Select makes ref the vault the package-level functions work on. ref is
+// either a vault name such as "work", kept as ~/.atlas/work.enc, or the path
+// of a vault file anywhere else; anything containing a path separator or
+// ending in ".enc" is taken as a path. Empty selects the default vault.
+func Select(ref string) error {
+	if ref == "" || ref == DefaultName {
+		selected.name, selected.path = DefaultName, ""
+		return nil
+	}
+	if !strings.ContainsAny(ref, `/\`) && !strings.HasSuffix(ref, Ext) {
+		path, err := VaultPath(ref)
+		if err != nil {
+			return err
+		}
+		selected.name, selected.path = ref, path
+		return nil
+	}
+
+	path, err := filepath.Abs(ref)
+	if err != nil {
+		return err
+	}
+	// A path into ~/.atlas is the same as giving the name
+	if dir, err := Dir(); err == nil && filepath.Dir(path) == dir {
+		base := filepath.Base(path)
+		if base == FileName {
+			return Select(DefaultName)
+		}
+		if name := strings.TrimSuffix(base, Ext); ValidateName(name) == nil {
+			return Select(name)
+		}
+	}
+	selected.name, selected.path = path, path
+	return nil
+}
+
+// Name returns the name of the selected vault, under which its settings are
+// kept in the configuration. Vaults outside ~/.atlas are named by their
+// absolute path.
+func Name() string {
+	if selected.name == "" {
+		return DefaultName
+	}
+	return selected.name
+}
+
+// GetVaultPath returns the full path to the selected vault file.
+func GetVaultPath() (string, error) {
+	if selected.path == "" {
+		return VaultPath(DefaultName)
+	}
+	return selected.path, nil
+}
+
+// EnsureDir ensures the directory of the selected vault exists.
+func EnsureDir() error {
+	path, err := GetVaultPath()
+	if err != nil {
+		return err
+	}
+	return fsError(os.MkdirAll(filepath.Dir(path), 0700))
+}
+
+// ListVaults returns the names of the vaults in ~/.atlas, the default one
+// first and the rest sorted.
+func ListVaults() ([]string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fsError(err)
+	}
+
+	var names []string
+	hasDefault := false
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), Ext) {
+			continue
+		}
+		if f.Name() == FileName {
+			hasDefault = true
+			continue
+		}
+		if name := strings.TrimSuffix(f.Name(), Ext); ValidateName(name) == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if hasDefault {
+		names = append([]string{DefaultName}, names...)
+	}
+	return names, nil
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// LockSuffix is appended to the vault path to name its lock file.
+const LockSuffix = ".lock"
+
+// Mode says whether a session may write the vault.
+type Mode int
+
+const (
+	// ReadWrite takes the vault lock, so no other process writes the vault
+	// until the session is closed.
+	ReadWrite Mode = iota
+
+	// ReadOnly neither takes nor respects the lock; the session cannot
+	// save.
+	ReadOnly
+)
+
+var (
+	// ErrInUse means another process holds the vault lock.
+	ErrInUse = errors.New("vault is in use")
+
+	// ErrReadOnly is returned when a read-only session tries to write.
+	ErrReadOnly = errors.New("vault is open read-only")
+)
+
+// staleAfter is how old an unreadable lock file must be before it is taken
+// to be left over, rather than being written by another process right now.
+const staleAfter = 10 * time.Second
+
+// LockInfo identifies the process holding a vault lock.
+type LockInfo struct {
+	PID   int       `json:"pid"`
+	Host  string    `json:"host"`
+	Since time.Time `json:"since"`
+}
+
+// InUseError is returned when another process holds the vault lock.
+type InUseError struct {
+	Path   string    // The lock file
+	Holder *LockInfo // Nil if the lock file could not be read
+}
+
+func (e *InUseError) Error() string {
+	if e.Holder == nil {
+		return ErrInUse.Error()
+	}
+	return fmt.Sprintf("%v: opened by PID %d on %s at %s", ErrInUse, e.Holder.PID, e.Holder.Host, e.Holder.Since.Local().Format("2006-01-02 15:04"))
+}
+
+func (e *InUseError) Is(target error) bool {
+	return target == ErrInUse
+}
+
+// fileLock is a held vault lock.
+type fileLock struct {
+	path string
+	info []byte
+}
+
+// lockVault takes the lock of the vault file at path. Locks left behind by
+// processes on this host that no longer run are replaced; locks from other
+// hosts cannot be checked and are only replaced once deleted by hand.
+func lockVault(path string) (*fileLock, error) {
+	host, _ := os.Hostname()
+	info, err := json.Marshal(LockInfo{PID: os.Getpid(), Host: host, Since: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+
+	lockPath := path + LockSuffix
+	for attempt := 0; ; attempt++ {
+		f, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			_, err = f.Write(info)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(lockPath)
+				return nil, fsError(err)
+			}
+			return &fileLock{path: lockPath, info: info}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fsError(err)
+		}
+
+		data, holder, stale := readLock(lockPath)
+		if !stale || attempt > 0 {
+			return nil, &InUseError{Path: lockPath, Holder: holder}
+		}
+		// Remove the stale lock only if nobody replaced it meanwhile
+		if again, err := os.ReadFile(lockPath); err == nil && string(again) == string(data) {
+			os.Remove(lockPath)
+		}
+	}
+}
+
+// readLock reads the lock file at path and reports whether it was left by
+// a process that is gone.
+func readLock(path string) (data []byte, holder *LockInfo, stale bool) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil, true
+	}
+	var info LockInfo
+	if err != nil || json.Unmarshal(data, &info) != nil {
+		// Still being written, or garbage; judge by its age
+		st, err := os.Stat(path)
+		return data, nil, err == nil && time.Since(st.ModTime()) > staleAfter
+	}
+	host, _ := os.Hostname()
+	return data, &info, info.Host == host && !processAlive(info.PID)
+}
+
+// release deletes the lock file, unless another process has replaced it.
+func (l *fileLock) release() {
+	if l == nil {
+		return
+	}
+	if data, err := os.ReadFile(l.path); err == nil && string(data) == string(l.info) {
+		os.Remove(l.path)
+	}
+}
+
+// LockHolder reports who holds the lock of the selected vault, or nil if
+// nobody does (or only a stale lock is left). It does not take the lock.
+func LockHolder() (*InUseError, error) {
+	path, err := GetVaultPath()
+	if err != nil {
+		return nil, err
+	}
+	lockPath := path + LockSuffix
+	_, holder, stale := readLock(lockPath)
+	if stale {
+		return nil, nil
+	}
+	return &InUseError{Path: lockPath, Holder: holder}, nil
+}
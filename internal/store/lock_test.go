@@ -0,0 +1,107 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fezcode/atlas.compass/internal/crypto"
+)
+
+// writeLock leaves a lock file next to the vault at path, as a process that
+// opened it would.
+func writeLock(t *testing.T, path string, info LockInfo) []byte {
+	t.Helper()
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+LockSuffix, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// unusedPID returns a PID that no process on this host has.
+func unusedPID(t *testing.T) int {
+	t.Helper()
+	for pid := 1 << 22; pid > 1<<21; pid-- {
+		if !processAlive(pid) {
+			return pid
+		}
+	}
+	t.Skip("cannot tell whether a process is running on this platform")
+	return 0
+}
+
+func TestLockTakesOverStaleLock(t *testing.T) {
+	_, s := newTestVault(t)
+	path := s.Path()
+	s.Close()
+
+	host, _ := os.Hostname()
+	writeLock(t, path, LockInfo{PID: unusedPID(t), Host: host, Since: time.Now().Add(-time.Hour)})
+	if holder, err := LockHolder(); err != nil || holder != nil {
+		t.Fatalf("LockHolder = %v, %v; want no holder", holder, err)
+	}
+
+	_, s, err := Load(crypto.PasswordCredential("pw"), ReadWrite)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	defer s.Close()
+	data, err := os.ReadFile(path + LockSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info LockInfo
+	if err := json.Unmarshal(data, &info); err != nil || info.PID != os.Getpid() {
+		t.Errorf("lock file = %s, want one naming PID %d", data, os.Getpid())
+	}
+
+	s.Close()
+	if _, err := os.Stat(path + LockSuffix); !os.IsNotExist(err) {
+		t.Errorf("Close left the lock file: %v", err)
+	}
+}
+
+func TestLockKeepsOtherHostsLock(t *testing.T) {
+	vault, s := newTestVault(t, testEntry("one", "base"))
+	path := s.Path()
+	s.Close()
+
+	host, _ := os.Hostname()
+	theirs := writeLock(t, path, LockInfo{PID: unusedPID(t), Host: host + ".elsewhere", Since: time.Now().Add(-time.Hour)})
+
+	_, _, err := Load(crypto.PasswordCredential("pw"), ReadWrite)
+	var inUse *InUseError
+	if !errors.As(err, &inUse) || !errors.Is(err, ErrInUse) {
+		t.Fatalf("Load = %v, want an *InUseError", err)
+	}
+	if inUse.Holder == nil || inUse.Holder.Host != host+".elsewhere" {
+		t.Errorf("Holder = %+v, want the other host", inUse.Holder)
+	}
+	if holder, err := LockHolder(); err != nil || holder == nil {
+		t.Errorf("LockHolder = %v, %v; want the other host", holder, err)
+	}
+
+	// Read-only sessions still open, but cannot save
+	got, s, err := Load(crypto.PasswordCredential("pw"), ReadOnly)
+	if err != nil {
+		t.Fatalf("Load(ReadOnly): %v", err)
+	}
+	defer s.Close()
+	if !s.ReadOnly() || len(got.Entries) != len(vault.Entries) {
+		t.Errorf("ReadOnly = %v with %d entries, want a read-only session with %d", s.ReadOnly(), len(got.Entries), len(vault.Entries))
+	}
+	if err := s.Save(got); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Save = %v, want ErrReadOnly", err)
+	}
+	s.Close()
+
+	if data, err := os.ReadFile(path + LockSuffix); err != nil || string(data) != string(theirs) {
+		t.Errorf("their lock file was changed: %s, %v", data, err)
+	}
+}
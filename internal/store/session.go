@@ -0,0 +1,366 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fezcode/atlas.compass/internal/crypto"
+	"github.com/fezcode/atlas.compass/pkg/model"
+)
+
+// Session is an unlocked vault. It holds the vault data key in a
+// crypto.SecretBuffer, so saving and slot maintenance need neither the
+// master password nor another key derivation.
+type Session struct {
+	path    string    // The vault file, fixed when the session opens
+	lock    *fileLock // Nil for read-only sessions
+	key     *crypto.SecretBuffer
+	slot    int
+	kind    crypto.SlotKind
+	params  crypto.Params
+	pending *crypto.Envelope // Not yet written: new or pre-envelope vault
+
+	// base is the vault as last read or written, and hash the SHA-256 of
+	// that file, so Save can tell whether someone else wrote it since
+	base *model.Vault
+	hash [sha256.Size]byte
+}
+
+func newSession(u *unlocked, path string) (*Session, error) {
+	key, err := crypto.SecretBufferFrom(u.dataKey)
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{path: path, key: key, slot: u.slot, params: u.params}
+	if u.env != nil && u.slot >= 0 {
+		s.kind = u.env.Slots[u.slot].Kind
+	}
+	return s, nil
+}
+
+// ReadOnly reports whether the session was opened without the vault lock,
+// so it cannot save.
+func (s *Session) ReadOnly() bool {
+	return s.lock == nil
+}
+
+// track records vault as read from the file contents data, for Save to
+// compare against.
+func (s *Session) track(vault *model.Vault, data []byte) {
+	s.base, s.hash = vault.Clone(), sha256.Sum256(data)
+}
+
+// Path returns the vault file the session reads and writes.
+func (s *Session) Path() string {
+	return s.path
+}
+
+// Slot returns the index of the key slot that opened the session.
+func (s *Session) Slot() int {
+	return s.slot
+}
+
+// Kind returns the kind of the key slot that opened the session.
+func (s *Session) Kind() crypto.SlotKind {
+	return s.kind
+}
+
+// Params returns the key derivation parameters of the slot that opened the
+// session, so callers can tell whether it is due for an upgrade.
+func (s *Session) Params() crypto.Params {
+	return s.params
+}
+
+// Key returns the vault data key, e.g. to hand it to the agent, or nil if
+// the vault has not been written in the current format yet. It must not be
+// kept past Close.
+func (s *Session) Key() []byte {
+	if s == nil || s.key == nil || s.pending != nil {
+		return nil
+	}
+	return s.key.Bytes()
+}
+
+// Close wipes the data key and releases the vault lock. The session cannot
+// be used afterwards.
+func (s *Session) Close() {
+	if s == nil {
+		return
+	}
+	s.key.Destroy()
+	s.key = nil
+	s.pending = nil
+	s.base = nil
+	s.lock.release()
+	s.lock = nil
+}
+
+// Save encrypts and writes the vault, keeping the data key and slots.
+//
+// If the file was written by someone else since the session last read or
+// wrote it, for example by a sync tool, their copy is merged in first and
+// vault is updated to the result. If both changed the same entries, nothing
+// is written and the error is a *ConflictError holding the merge. The
+// session keeps building on the copy it knew, so saving vault again reports
+// the same conflicts; resolve them in the merge and pass it to
+// SaveResolved instead.
+func (s *Session) Save(vault *model.Vault) error {
+	base := s.base
+	if base == nil {
+		base = &model.Vault{}
+	}
+	return s.saveOver(vault, base, s.hash)
+}
+
+// SaveResolved writes merged, the Merged vault of conflict with every
+// conflict resolved. It is written as is only if the file still is the copy
+// the conflict was found against; if it changed yet again, merged is merged
+// with the newer copy like in Save, which may report new conflicts.
+func (s *Session) SaveResolved(merged *model.Vault, conflict *ConflictError) error {
+	return s.saveOver(merged, conflict.theirs, conflict.Hash)
+}
+
+// saveOver writes vault, which holds changes made to base, the entries of
+// the file whose SHA-256 is hash.
+func (s *Session) saveOver(vault, base *model.Vault, hash [sha256.Size]byte) error {
+	env, plaintext, sum, err := s.envelope()
+	if err != nil {
+		return err
+	}
+	defer crypto.Wipe(plaintext)
+
+	if sum != hash {
+		var theirs model.Vault
+		if err := json.Unmarshal(plaintext, &theirs); err != nil {
+			return fmt.Errorf("%w: %w", ErrCorrupt, err)
+		}
+		merged, conflicts := model.Merge(base, vault, &theirs)
+		if len(conflicts) > 0 {
+			return &ConflictError{Merged: merged, Conflicts: conflicts, Hash: sum, theirs: &theirs}
+		}
+		*vault = *merged
+	}
+
+	jsonBytes, err := json.Marshal(vault)
+	if err != nil {
+		return err
+	}
+	defer crypto.Wipe(jsonBytes)
+
+	if err := env.Seal(jsonBytes, s.key.Bytes()); err != nil {
+		return err
+	}
+	if err := s.write(env, true); err != nil {
+		return err
+	}
+	s.base = vault.Clone()
+	return nil
+}
+
+// Rewrap derives the slot that opened the session again from cred with
+// params, e.g. to raise its cost. cred must be the secret of that slot. A
+// plain password slot stays plain even if cred carries a keyfile; keyfiles
+// are enabled explicitly with ChangePassword.
+func (s *Session) Rewrap(cred crypto.Credential, params crypto.Params) error {
+	env, plaintext, sum, err := s.envelope()
+	if err != nil {
+		return err
+	}
+	crypto.Wipe(plaintext)
+
+	if s.kind == crypto.SlotPassword {
+		cred.Keyfile = nil
+	}
+	if err := env.ReplaceSlot(s.slot, cred, params, s.key.Bytes()); err != nil {
+		return err
+	}
+	if err := s.write(env, sum == s.hash); err != nil {
+		return err
+	}
+	s.params = params
+	return nil
+}
+
+// ChangePassword rewraps the vault data key under a new master password,
+// replacing the password slot that current opens. The encrypted entries are
+// left untouched. Giving next a keyfile turns the slot into a
+// password+keyfile slot; leaving it out turns it back into a plain one.
+func (s *Session) ChangePassword(current, next crypto.Credential, params crypto.Params) error {
+	env, plaintext, sum, err := s.envelope()
+	if err != nil {
+		return err
+	}
+	crypto.Wipe(plaintext)
+
+	dataKey, slot, err := env.Unwrap(current)
+	if err != nil {
+		return err
+	}
+	crypto.Wipe(dataKey)
+
+	if err := env.ReplaceSlot(slot, next, params, s.key.Bytes()); err != nil {
+		return err
+	}
+	if err := s.write(env, sum == s.hash); err != nil {
+		return err
+	}
+	if slot == s.slot {
+		s.kind = env.Slots[slot].Kind
+		s.params = params
+	}
+	return nil
+}
+
+// ResetPassword replaces every password slot with a single slot for next.
+// It is meant for sessions opened with another secret, such as a recovery
+// key, when the master password is lost. Other slots are kept.
+func (s *Session) ResetPassword(next crypto.Credential, params crypto.Params) error {
+	env, plaintext, sum, err := s.envelope()
+	if err != nil {
+		return err
+	}
+	crypto.Wipe(plaintext)
+
+	opener := -1
+	kept := env.Slots[:0:0]
+	for i, slot := range env.Slots {
+		if slot.Kind == crypto.SlotPassword || slot.Kind == crypto.SlotPasswordKeyfile {
+			continue
+		}
+		if i == s.slot {
+			opener = len(kept)
+		}
+		kept = append(kept, slot)
+	}
+	env.Slots = kept
+
+	if err := env.AddSlot(next, params, s.key.Bytes()); err != nil {
+		return err
+	}
+	if err := s.write(env, sum == s.hash); err != nil {
+		return err
+	}
+
+	// The session moves to the new slot if its own was a password slot
+	s.slot = opener
+	if opener < 0 {
+		s.slot = len(env.Slots) - 1
+	}
+	s.kind = env.Slots[s.slot].Kind
+	s.params = env.Slots[s.slot].KDF
+	return nil
+}
+
+// SetCipher re-encrypts the vault payload with c. Key slots are kept as
+// they are.
+func (s *Session) SetCipher(c crypto.Cipher) error {
+	env, plaintext, sum, err := s.envelope()
+	if err != nil {
+		return err
+	}
+	defer crypto.Wipe(plaintext)
+
+	env.Cipher = c.ID()
+	if err := env.Seal(plaintext, s.key.Bytes()); err != nil {
+		return err
+	}
+	return s.write(env, sum == s.hash)
+}
+
+// AddSlot adds a key slot for cred.
+func (s *Session) AddSlot(cred crypto.Credential, params crypto.Params) error {
+	env, plaintext, sum, err := s.envelope()
+	if err != nil {
+		return err
+	}
+	crypto.Wipe(plaintext)
+
+	if err := env.AddSlot(cred, params, s.key.Bytes()); err != nil {
+		return err
+	}
+	return s.write(env, sum == s.hash)
+}
+
+// RemoveSlot deletes the key slot at index. The last remaining slot can
+// never be removed, and neither can the slot that opened the session, so
+// the caller is never locked out mid-session.
+func (s *Session) RemoveSlot(index int) error {
+	if index == s.slot {
+		return fmt.Errorf("slot #%d is the one this session was unlocked with", index)
+	}
+
+	env, plaintext, sum, err := s.envelope()
+	if err != nil {
+		return err
+	}
+	crypto.Wipe(plaintext)
+
+	if err := env.RemoveSlot(index); err != nil {
+		return err
+	}
+	if err := s.write(env, sum == s.hash); err != nil {
+		return err
+	}
+	if index < s.slot {
+		s.slot--
+	}
+	return nil
+}
+
+// envelope returns the envelope to modify, its decrypted payload and the
+// SHA-256 of the file it came from. It is read from disk unless the session
+// has one pending, and checked to still open with the session's data key.
+func (s *Session) envelope() (*crypto.Envelope, []byte, [sha256.Size]byte, error) {
+	sum := s.hash
+	if s.key == nil {
+		return nil, nil, sum, fmt.Errorf("vault session is closed")
+	}
+
+	env := s.pending
+	if env == nil {
+		data, err := readVaultFile(s.path)
+		if err != nil {
+			return nil, nil, sum, err
+		}
+		if crypto.FormatVersion(data) != crypto.Version2 {
+			return nil, nil, sum, fmt.Errorf("%w: vault file was replaced by an older format", crypto.ErrBadHeader)
+		}
+		if env, err = crypto.ParseEnvelope(data); err != nil {
+			return nil, nil, sum, err
+		}
+		sum = sha256.Sum256(data)
+	}
+	if env.Ciphertext == nil {
+		return env, nil, sum, nil // New vault, nothing sealed yet
+	}
+
+	plaintext, err := env.Open(s.key.Bytes())
+	if err != nil {
+		return nil, nil, sum, fmt.Errorf("vault file no longer matches this session: %w", err)
+	}
+	return env, plaintext, sum, nil
+}
+
+// write stores env on disk and clears any pending envelope. current says
+// whether its payload holds the entries of base; only then does the new
+// file become the one Save compares against.
+func (s *Session) write(env *crypto.Envelope, current bool) error {
+	if s.lock == nil {
+		return ErrReadOnly
+	}
+	if err := fsError(os.MkdirAll(filepath.Dir(s.path), 0700)); err != nil {
+		return err
+	}
+	sum, err := writeEnvelope(s.path, env)
+	if err != nil {
+		return err
+	}
+	if current {
+		s.hash = sum
+	}
+	s.pending = nil
+	return nil
+}
@@ -0,0 +1,178 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/fezcode/atlas.compass/internal/crypto"
+	"github.com/fezcode/atlas.compass/pkg/model"
+)
+
+// testParams keeps key derivation cheap in tests.
+var testParams = crypto.Params{Time: 1, Memory: 8, Threads: 1}
+
+// useTempHome points the store at an empty home directory and selects the
+// default vault.
+func useTempHome(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	if err := Select(DefaultName); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestVault creates the default vault in an empty home directory, with
+// password "pw", and returns it with its session.
+func newTestVault(t *testing.T, entries ...model.Entry) (*model.Vault, *Session) {
+	t.Helper()
+	useTempHome(t)
+	vault := &model.Vault{Entries: entries}
+	s, err := Create(vault, crypto.PasswordCredential("pw"), testParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return vault, s
+}
+
+// openCopy opens the vault at path as another machine sharing the file
+// through a sync tool would: writable, but without seeing our lock.
+func openCopy(t *testing.T, path string) (*model.Vault, *Session) {
+	t.Helper()
+	vault, s, err := load(path, crypto.PasswordCredential("pw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.lock = &fileLock{} // Held elsewhere; releasing it does nothing
+	t.Cleanup(s.Close)
+	return vault, s
+}
+
+// readBack returns the entries on disk as "title:notes".
+func readBack(t *testing.T, path string) []string {
+	t.Helper()
+	vault, s, err := load(path, crypto.PasswordCredential("pw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	var got []string
+	for _, e := range vault.Entries {
+		got = append(got, e.Title+":"+e.Notes)
+	}
+	return got
+}
+
+func testEntry(title, notes string) model.Entry {
+	now := time.Now()
+	return model.Entry{ID: model.NewID(), Title: title, Notes: notes, CreatedAt: now, UpdatedAt: now}
+}
+
+// edit changes the notes of entry i, as the editor does.
+func edit(v *model.Vault, i int, notes string) {
+	v.Entries[i].Notes = notes
+	v.Entries[i].UpdatedAt = time.Now()
+}
+
+func TestSaveMergesChangesOnDisk(t *testing.T) {
+	ours, a := newTestVault(t, testEntry("one", "base"), testEntry("two", "base"))
+	theirs, b := openCopy(t, a.Path())
+
+	edit(theirs, 1, "theirs")
+	theirs.Entries = append(theirs.Entries, testEntry("three", "theirs"))
+	if err := b.Save(theirs); err != nil {
+		t.Fatal(err)
+	}
+
+	edit(ours, 0, "ours")
+	if err := a.Save(ours); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	want := []string{"one:ours", "two:theirs", "three:theirs"}
+	if got := readBack(t, a.Path()); !slices.Equal(got, want) {
+		t.Errorf("on disk = %v, want %v", got, want)
+	}
+	if len(ours.Entries) != 3 {
+		t.Errorf("Save did not update the vault to the merge: %d entries", len(ours.Entries))
+	}
+
+	// The merge is the new base: saving again writes without conflicts
+	edit(ours, 2, "ours again")
+	if err := a.Save(ours); err != nil {
+		t.Fatalf("second Save: %v", err)
+	}
+}
+
+func TestSaveConflict(t *testing.T) {
+	ours, a := newTestVault(t, testEntry("one", "base"), testEntry("two", "base"))
+	theirs, b := openCopy(t, a.Path())
+
+	edit(theirs, 0, "theirs")
+	if err := b.Save(theirs); err != nil {
+		t.Fatal(err)
+	}
+	edit(ours, 0, "ours")
+	edit(ours, 1, "ours")
+
+	err := a.Save(ours)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrConflict) {
+		t.Fatalf("Save = %v, want a *ConflictError", err)
+	}
+	if len(conflict.Conflicts) != 1 || conflict.Conflicts[0].Ours.Notes != "ours" || conflict.Conflicts[0].Theirs.Notes != "theirs" {
+		t.Fatalf("conflicts = %+v, want entry one", conflict.Conflicts)
+	}
+	onDisk := []string{"one:theirs", "two:base"}
+	if got := readBack(t, a.Path()); !slices.Equal(got, onDisk) {
+		t.Errorf("on disk = %v after a conflict, want %v", got, onDisk)
+	}
+
+	// Saving the unresolved vault again must not overwrite their change
+	if err := a.Save(ours); !errors.As(err, &conflict) {
+		t.Fatalf("second Save = %v, want a *ConflictError", err)
+	}
+	if got := readBack(t, a.Path()); !slices.Equal(got, onDisk) {
+		t.Errorf("on disk = %v after saving again, want %v", got, onDisk)
+	}
+
+	conflict.Merged.Resolve(conflict.Conflicts[0], model.KeepOurs)
+	if err := a.SaveResolved(conflict.Merged, conflict); err != nil {
+		t.Fatalf("SaveResolved: %v", err)
+	}
+	want := []string{"one:ours", "two:ours"}
+	if got := readBack(t, a.Path()); !slices.Equal(got, want) {
+		t.Errorf("on disk = %v, want %v", got, want)
+	}
+}
+
+func TestSaveResolvedMergesLaterChanges(t *testing.T) {
+	ours, a := newTestVault(t, testEntry("one", "base"), testEntry("two", "base"))
+	theirs, b := openCopy(t, a.Path())
+
+	edit(theirs, 0, "theirs")
+	if err := b.Save(theirs); err != nil {
+		t.Fatal(err)
+	}
+	edit(ours, 0, "ours")
+	var conflict *ConflictError
+	if err := a.Save(ours); !errors.As(err, &conflict) {
+		t.Fatalf("Save = %v, want a *ConflictError", err)
+	}
+
+	// They write again while we resolve
+	edit(theirs, 1, "theirs")
+	if err := b.Save(theirs); err != nil {
+		t.Fatal(err)
+	}
+
+	conflict.Merged.Resolve(conflict.Conflicts[0], model.KeepBoth)
+	if err := a.SaveResolved(conflict.Merged, conflict); err != nil {
+		t.Fatalf("SaveResolved: %v", err)
+	}
+	want := []string{"one:ours", "two:theirs", "one (conflicting copy):theirs"}
+	if got := readBack(t, a.Path()); !slices.Equal(got, want) {
+		t.Errorf("on disk = %v, want %v", got, want)
+	}
+}
@@ -1,96 +1,357 @@
 package store
 
 import (
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 
 	"github.com/fezcode/atlas.compass/internal/crypto"
 	"github.com/fezcode/atlas.compass/pkg/model"
 )
 
-const (
-	DirName  = ".atlas"
-	FileName = "compass.enc"
-)
+// Load reads and decrypts the vault with the given credential and returns
+// it with a Session for saving it again. Every format version is accepted,
+// including legacy headerless files; those are converted to the envelope
+// format in memory and written that way on the first save.
+//
+// In ReadWrite mode the vault lock is taken first and held until the
+// session is closed; if another process holds it, the error is an
+// *InUseError.
+//
+// The caller should wipe cred once it has no further use for it, and must
+// Close the session.
+func Load(cred crypto.Credential, mode Mode) (*model.Vault, *Session, error) {
+	path, lock, err := openSelected(mode)
+	if err != nil {
+		return nil, nil, err
+	}
+	vault, s, err := load(path, cred)
+	if err != nil {
+		lock.release()
+		return nil, nil, err
+	}
+	s.lock = lock
+	return vault, s, nil
+}
 
-// GetVaultPath returns the full path to the encrypted vault file.
-func GetVaultPath() (string, error) {
-	home, err := os.UserHomeDir()
+func load(path string, cred crypto.Credential) (*model.Vault, *Session, error) {
+	data, err := readVaultFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	u, err := unlock(data, cred)
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
-	return filepath.Join(home, DirName, FileName), nil
+	defer crypto.Wipe(u.plaintext)
+
+	vault, err := u.vault()
+	if err != nil {
+		crypto.Wipe(u.dataKey)
+		return nil, nil, err
+	}
+
+	if u.env == nil {
+		// Pre-envelope file: wrap a fresh data key for cred, with the same
+		// cost the file was written with
+		if u.dataKey, err = crypto.NewDataKey(); err != nil {
+			return nil, nil, err
+		}
+		u.env = crypto.NewEnvelope()
+		if err := u.env.AddSlot(cred, u.params, u.dataKey); err != nil {
+			crypto.Wipe(u.dataKey)
+			return nil, nil, err
+		}
+		if err := u.env.Seal(u.plaintext, u.dataKey); err != nil {
+			crypto.Wipe(u.dataKey)
+			return nil, nil, err
+		}
+		s, err := newSession(u, path)
+		if err != nil {
+			return nil, nil, err
+		}
+		s.track(vault, data)
+		s.pending = u.env
+		return vault, s, nil
+	}
+
+	s, err := newSession(u, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.track(vault, data)
+	return vault, s, nil
 }
 
-// EnsureDir ensures the config directory exists.
-func EnsureDir() error {
-	home, err := os.UserHomeDir()
+// LoadKey decrypts the vault with a data key kept from an earlier session,
+// such as one held by the agent, skipping key derivation. slot is the index
+// of the key slot that first unwrapped the key. mode works as for Load. The
+// caller should wipe key and must Close the session.
+func LoadKey(key []byte, slot int, mode Mode) (*model.Vault, *Session, error) {
+	path, lock, err := openSelected(mode)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	dirPath := filepath.Join(home, DirName)
-	return os.MkdirAll(dirPath, 0700)
+	vault, s, err := loadKey(path, key, slot)
+	if err != nil {
+		lock.release()
+		return nil, nil, err
+	}
+	s.lock = lock
+	return vault, s, nil
 }
 
-// Load reads and decrypts the vault.
-func Load(password string) (*model.Vault, error) {
-	path, err := GetVaultPath()
+func loadKey(path string, key []byte, slot int) (*model.Vault, *Session, error) {
+	data, err := readVaultFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if crypto.FormatVersion(data) != crypto.Version2 {
+		return nil, nil, fmt.Errorf("%w: vault file was replaced by an older format", crypto.ErrBadHeader)
+	}
+	env, err := crypto.ParseEnvelope(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext, err := env.Open(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: key no longer opens this vault", crypto.ErrBadCredentials)
+	}
+	defer crypto.Wipe(plaintext)
+
+	if slot < 0 || slot >= len(env.Slots) {
+		slot = -1
+	}
+	u := &unlocked{plaintext: plaintext, env: env, dataKey: append([]byte(nil), key...), slot: slot}
+	if slot >= 0 {
+		u.params = env.Slots[slot].KDF
+	}
+	vault, err := u.vault()
+	if err != nil {
+		crypto.Wipe(u.dataKey)
+		return nil, nil, err
+	}
+	s, err := newSession(u, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.track(vault, data)
+	return vault, s, nil
+}
+
+// Create writes a new vault protected by a single slot for cred, derived
+// with params. It refuses to replace an existing vault. The session holds
+// the vault lock.
+func Create(vault *model.Vault, cred crypto.Credential, params crypto.Params) (*Session, error) {
+	if err := EnsureDir(); err != nil {
+		return nil, err
+	}
+	path, lock, err := openSelected(ReadWrite)
 	if err != nil {
 		return nil, err
 	}
+	if Exists() {
+		lock.release()
+		return nil, errors.New("a vault already exists")
+	}
 
-	data, err := os.ReadFile(path)
-	if os.IsNotExist(err) {
-		// Return empty vault if file doesn't exist
-		return &model.Vault{Entries: []model.Entry{}}, nil
+	dataKey, err := crypto.NewDataKey()
+	if err != nil {
+		lock.release()
+		return nil, err
 	}
+	env := crypto.NewEnvelope()
+	if err := env.AddSlot(cred, params, dataKey); err != nil {
+		crypto.Wipe(dataKey)
+		lock.release()
+		return nil, err
+	}
+
+	s, err := newSession(&unlocked{params: params, env: env, dataKey: dataKey}, path)
 	if err != nil {
+		lock.release()
+		return nil, err
+	}
+	s.lock = lock
+	s.pending = env
+	if err := s.Save(vault); err != nil {
+		s.Close()
 		return nil, err
 	}
+	return s, nil
+}
 
-	plaintext, err := crypto.Decrypt(data, password)
+// Cipher returns the cipher the vault payload is encrypted with. It reads
+// only the file header; no credential is needed.
+func Cipher() (crypto.Cipher, error) {
+	_, data, err := readSelected()
+	if err != nil {
+		return nil, err
+	}
+	if crypto.FormatVersion(data) != crypto.Version2 {
+		return crypto.AES256GCM, nil
+	}
+	env, err := crypto.ParseEnvelope(data)
 	if err != nil {
 		return nil, err
 	}
+	return crypto.CipherByID(env.Cipher)
+}
 
-	var vault model.Vault
-	if err := json.Unmarshal(plaintext, &vault); err != nil {
-		return nil, fmt.Errorf("corrupted vault data: %w", err)
+// SlotInfo describes a key slot without revealing anything secret.
+type SlotInfo struct {
+	Index int
+	Kind  crypto.SlotKind
+	KDF   crypto.Params
+}
+
+// ListSlots returns the key slots of the vault. It does not need a
+// credential; pre-envelope vaults report a single password slot.
+func ListSlots() ([]SlotInfo, error) {
+	_, data, err := readSelected()
+	if err != nil {
+		return nil, err
+	}
+
+	switch crypto.FormatVersion(data) {
+	case crypto.VersionLegacy:
+		return []SlotInfo{{Kind: crypto.SlotPassword, KDF: crypto.LegacyParams}}, nil
+	case crypto.Version1:
+		header, err := crypto.ParseHeader(data)
+		if err != nil {
+			return nil, err
+		}
+		return []SlotInfo{{Kind: crypto.SlotPassword, KDF: header.KDF}}, nil
+	}
+
+	env, err := crypto.ParseEnvelope(data)
+	if err != nil {
+		return nil, err
 	}
+	slots := make([]SlotInfo, len(env.Slots))
+	for i, s := range env.Slots {
+		slots[i] = SlotInfo{Index: i, Kind: s.Kind, KDF: s.KDF}
+	}
+	return slots, nil
+}
+
+// unlocked is a vault file decrypted in memory.
+type unlocked struct {
+	plaintext []byte
+	params    crypto.Params    // KDF parameters of the secret that opened it
+	env       *crypto.Envelope // nil for pre-envelope formats
+	dataKey   []byte
+	slot      int
+}
 
+func (u *unlocked) vault() (*model.Vault, error) {
+	var vault model.Vault
+	if err := json.Unmarshal(u.plaintext, &vault); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCorrupt, err)
+	}
 	return &vault, nil
 }
 
-// Save encrypts and writes the vault to disk.
-func Save(vault *model.Vault, password string) error {
-	if err := EnsureDir(); err != nil {
-		return err
+// unlock decrypts a vault file of any format version with cred. Formats
+// older than the envelope only support the master password.
+func unlock(data []byte, cred crypto.Credential) (*unlocked, error) {
+	switch crypto.FormatVersion(data) {
+	case crypto.VersionLegacy, crypto.Version1:
+		if cred.Kind != crypto.SlotPassword {
+			return nil, fmt.Errorf("this vault can only be unlocked with its master password until it is saved again")
+		}
+		params := crypto.LegacyParams
+		if crypto.FormatVersion(data) == crypto.Version1 {
+			header, err := crypto.ParseHeader(data)
+			if err != nil {
+				return nil, err
+			}
+			params = header.KDF
+		}
+		plaintext, err := crypto.DecryptSecret(data, cred.Secret)
+		if err != nil {
+			return nil, err
+		}
+		return &unlocked{plaintext: plaintext, params: params}, nil
+	}
+
+	env, err := crypto.ParseEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, slot, err := env.Unwrap(cred)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := env.Open(dataKey)
+	if err != nil {
+		crypto.Wipe(dataKey)
+		return nil, err
 	}
+	return &unlocked{
+		plaintext: plaintext,
+		params:    env.Slots[slot].KDF,
+		env:       env,
+		dataKey:   dataKey,
+		slot:      slot,
+	}, nil
+}
 
+// openSelected returns the path of the selected vault, with its lock taken in
+// ReadWrite mode.
+func openSelected(mode Mode) (string, *fileLock, error) {
 	path, err := GetVaultPath()
 	if err != nil {
-		return err
+		return "", nil, err
+	}
+	if mode == ReadOnly {
+		return path, nil, nil
+	}
+	lock, err := lockVault(path)
+	if err != nil {
+		return "", nil, err
 	}
+	return path, lock, nil
+}
 
-	jsonBytes, err := json.Marshal(vault)
+// readSelected reads the selected vault file and returns it with its path.
+func readSelected() (string, []byte, error) {
+	path, err := GetVaultPath()
 	if err != nil {
-		return err
+		return "", nil, err
 	}
+	data, err := readVaultFile(path)
+	return path, data, err
+}
+
+func readVaultFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	return data, fsError(err)
+}
 
-	encryptedData, err := crypto.Encrypt(jsonBytes, password)
+// writeEnvelope writes env to path and returns the SHA-256 of the file.
+func writeEnvelope(path string, env *crypto.Envelope) ([sha256.Size]byte, error) {
+	encryptedData, err := env.MarshalBinary()
 	if err != nil {
-		return err
+		return [sha256.Size]byte{}, err
 	}
 
 	// Atomic write: write to temp file then rename
 	tmpPath := path + ".tmp"
 	if err := os.WriteFile(tmpPath, encryptedData, 0600); err != nil {
-		return err
+		return [sha256.Size]byte{}, fsError(err)
 	}
 
-	return os.Rename(tmpPath, path)
+	return sha256.Sum256(encryptedData), fsError(os.Rename(tmpPath, path))
 }
 
 // Exists checks if the vault file exists.
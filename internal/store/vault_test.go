@@ -0,0 +1,151 @@
+package store
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fezcode/atlas.compass/internal/crypto"
+	"github.com/fezcode/atlas.compass/pkg/model"
+)
+
+// sealLegacy encrypts plaintext as the versions before the file header
+// did: Salt + Nonce + AES-GCM ciphertext, keyed with crypto.LegacyParams.
+func sealLegacy(t *testing.T, plaintext []byte, password string) []byte {
+	t.Helper()
+	salt, nonce := randomBytes(t, crypto.SaltSize), randomBytes(t, crypto.NonceSize)
+	aead := legacyGCM(t, crypto.DeriveKey([]byte(password), salt, crypto.LegacyParams))
+	return append(append(salt, nonce...), aead.Seal(nil, nonce, plaintext, nil)...)
+}
+
+// sealV1 encrypts plaintext in the Version1 format: a header, bound as
+// associated data, followed by the AES-GCM ciphertext.
+func sealV1(t *testing.T, plaintext []byte, password string, params crypto.Params) []byte {
+	t.Helper()
+	h := crypto.Header{
+		Version: crypto.Version1,
+		Cipher:  crypto.CipherAES256GCM,
+		KDF:     params,
+		Salt:    randomBytes(t, crypto.SaltSize),
+		Nonce:   randomBytes(t, crypto.NonceSize),
+	}
+	header, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead := legacyGCM(t, crypto.DeriveKey([]byte(password), h.Salt, params))
+	return aead.Seal(header, h.Nonce, plaintext, header)
+}
+
+func legacyGCM(t *testing.T, key []byte) cipher.AEAD {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return aead
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestLoadOlderFormats(t *testing.T) {
+	want := &model.Vault{Entries: []model.Entry{testEntry("one", "from an old version")}}
+	plaintext, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		data    func(t *testing.T) []byte
+		version uint8
+		params  crypto.Params
+	}{
+		{"headerless", func(t *testing.T) []byte { return sealLegacy(t, plaintext, "pw") }, crypto.VersionLegacy, crypto.LegacyParams},
+		{"version 1", func(t *testing.T) []byte { return sealV1(t, plaintext, "pw", testParams) }, crypto.Version1, testParams},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempHome(t)
+			if err := EnsureDir(); err != nil {
+				t.Fatal(err)
+			}
+			path, err := GetVaultPath()
+			if err != nil {
+				t.Fatal(err)
+			}
+			old := tt.data(t)
+			if err := os.WriteFile(path, old, 0600); err != nil {
+				t.Fatal(err)
+			}
+			if v := crypto.FormatVersion(old); v != tt.version {
+				t.Fatalf("test file has version %d, want %d", v, tt.version)
+			}
+
+			if _, _, err := Load(crypto.RecoveryCredential(make([]byte, crypto.KeySize)), ReadOnly); err == nil {
+				t.Error("an old vault opened without its master password")
+			}
+
+			vault, s, err := Load(crypto.PasswordCredential("pw"), ReadWrite)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			defer s.Close()
+			if len(vault.Entries) != 1 || !vault.Entries[0].Equal(want.Entries[0]) {
+				t.Errorf("entries = %+v, want %+v", vault.Entries, want.Entries)
+			}
+			if s.Params() != tt.params {
+				t.Errorf("Params = %s, want %s", s.Params(), tt.params)
+			}
+
+			// Opening alone leaves the file as it was
+			if data, _ := os.ReadFile(path); !bytes.Equal(data, old) {
+				t.Error("Load rewrote the vault before it was saved")
+			}
+
+			vault.Entries = append(vault.Entries, testEntry("two", "new"))
+			if err := s.Save(vault); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v := crypto.FormatVersion(data); v != crypto.Version2 {
+				t.Fatalf("saved vault has version %d, want %d", v, crypto.Version2)
+			}
+			env, err := crypto.ParseEnvelope(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(env.Slots) != 1 || env.Slots[0].Kind != crypto.SlotPassword || env.Slots[0].KDF != tt.params {
+				t.Errorf("slots = %+v, want one password slot with %s", env.Slots, tt.params)
+			}
+			s.Close()
+
+			if got := readBack(t, path); len(got) != 2 || got[0] != "one:from an old version" || got[1] != "two:new" {
+				t.Errorf("after saving, the vault holds %v", got)
+			}
+			if matches, _ := filepath.Glob(path + ".*"); len(matches) != 0 {
+				t.Errorf("files left next to the vault: %v", matches)
+			}
+		})
+	}
+}
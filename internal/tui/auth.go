@@ -1,28 +1,70 @@
 package tui
 
 import (
+	"fmt"
+
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/fezcode/atlas.compass/internal/store"
+)
+
+// AuthMode selects which kind of secret the auth screen asks for.
+type AuthMode int
+
+const (
+	AuthPassword AuthMode = iota
+	AuthKeyfile
+	AuthRecovery
+	AuthModeCount
 )
 
 type AuthModel struct {
 	Input     textinput.Model
+	Mode      AuthMode
 	Err       error
+	Hint      string // Suggested next step for Err
+	Notice    string // Why the vault was locked, if it was
 	IsLoading bool
+	Vault     string   // Name of the selected vault
+	Vaults    []string // Vaults the picker switches between
+	ReadOnly  bool     // Unlocking opens the vault read-only
 }
 
 func NewAuthModel() AuthModel {
 	ti := textinput.New()
-	ti.Placeholder = "Master Password"
-	ti.EchoMode = textinput.EchoPassword
 	ti.Focus()
-	ti.CharLimit = 156
-	ti.Width = 30
 
-	return AuthModel{
+	m := AuthModel{
 		Input: ti,
 	}
+	m.SetMode(AuthPassword)
+	return m
+}
+
+// SetMode switches the kind of secret being asked for and clears the input.
+func (m *AuthModel) SetMode(mode AuthMode) {
+	m.Mode = mode
+	m.Err = nil
+	m.Hint = ""
+	m.Input.SetValue("")
+	switch mode {
+	case AuthPassword:
+		m.Input.Placeholder = "Master Password"
+		m.Input.EchoMode = textinput.EchoPassword
+		m.Input.CharLimit = 156
+		m.Input.Width = 30
+	case AuthKeyfile:
+		m.Input.Placeholder = "Path to Keyfile"
+		m.Input.EchoMode = textinput.EchoNormal
+		m.Input.CharLimit = 1024
+		m.Input.Width = 30
+	case AuthRecovery:
+		m.Input.Placeholder = "Recovery key words"
+		m.Input.EchoMode = textinput.EchoNormal
+		m.Input.CharLimit = 512
+		m.Input.Width = 60
+	}
 }
 
 func (m AuthModel) Init() tea.Cmd {
@@ -30,6 +72,11 @@ func (m AuthModel) Init() tea.Cmd {
 }
 
 func (m AuthModel) Update(msg tea.Msg) (AuthModel, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok && msg.Type == tea.KeyTab {
+		m.SetMode((m.Mode + 1) % AuthModeCount)
+		return m, nil
+	}
+
 	var cmd tea.Cmd
 	m.Input, cmd = m.Input.Update(msg)
 	return m, cmd
@@ -37,17 +84,58 @@ func (m AuthModel) Update(msg tea.Msg) (AuthModel, tea.Cmd) {
 
 func (m AuthModel) View() string {
 	title := StyleAuthHeader.Render("ATLAS COMPASS")
-	
+
 	input := m.Input.View()
-	
-	hint := StyleSubtext.Render("Enter Master Password to Unlock")
-	
+
+	hintText := "Enter Master Password to Unlock"
+	switch m.Mode {
+	case AuthKeyfile:
+		hintText = "Enter Keyfile Path to Unlock"
+	case AuthRecovery:
+		hintText = "Use Recovery Key: enter its words, separated by spaces"
+	}
+	hint := StyleSubtext.Render(hintText)
+
 	errView := ""
 	if m.Err != nil {
 		errView = lipgloss.NewStyle().Foreground(ColorError).MarginTop(1).Render(m.Err.Error())
+		if m.Hint != "" {
+			errView = lipgloss.JoinVertical(lipgloss.Center, errView, StyleSubtext.Copy().Width(50).Align(lipgloss.Center).Render(m.Hint))
+		}
 	}
 
-	content := lipgloss.JoinVertical(lipgloss.Center, title, hint, input, errView)
-	
+	switchHint := StyleSubtext.Render("[tab] switch unlock method")
+
+	parts := []string{title}
+	if m.Notice != "" {
+		parts = append(parts, lipgloss.NewStyle().Foreground(ColorCyan).MarginBottom(1).Render(m.Notice))
+	}
+	if picker := vaultPicker(m.Vaults, m.Vault); picker != "" {
+		parts = append(parts, picker)
+	}
+	if m.ReadOnly {
+		parts = append(parts, lipgloss.NewStyle().Foreground(ColorSecondary).MarginBottom(1).Render("Read-only: the vault is in use elsewhere"))
+	}
+	parts = append(parts, hint, input, errView, switchHint)
+	if len(m.Vaults) > 1 {
+		parts = append(parts, StyleSubtext.Render("[ctrl+n/ctrl+p] switch vault"))
+	}
+	content := lipgloss.JoinVertical(lipgloss.Center, parts...)
+
 	return StyleAuthBox.Render(content)
 }
+
+// vaultPicker shows which vault is selected, and its place among the others
+// if there are several. It is empty when there is only the default vault.
+func vaultPicker(vaults []string, current string) string {
+	if len(vaults) < 2 && current == store.DefaultName {
+		return ""
+	}
+	label := "Vault: " + current
+	for i, name := range vaults {
+		if name == current && len(vaults) > 1 {
+			label = fmt.Sprintf("Vault: ‹ %s › (%d/%d)", current, i+1, len(vaults))
+		}
+	}
+	return lipgloss.NewStyle().Foreground(ColorCyan).MarginBottom(1).Render(label)
+}
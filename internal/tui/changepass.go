@@ -109,9 +109,9 @@ func (m ChangePassModel) View() string {
 	b.WriteString(StyleListHeader.Render("Change Master Password"))
 	b.WriteString("\n\n")
 	
-	b.WriteString(StyleSubtext.Render("WARNING: If you proceed, your vault will be re-encrypted."))
+	b.WriteString(StyleSubtext.Render("WARNING: If you proceed, your vault key will be rewrapped."))
 	b.WriteString("\n")
-	b.WriteString(StyleSubtext.Render("There is NO UNDO."))
+	b.WriteString(StyleSubtext.Render("The old password stops working. There is NO UNDO."))
 	b.WriteString("\n\n")
 
 	for i, input := range m.Inputs {
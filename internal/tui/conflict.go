@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fezcode/atlas.compass/internal/store"
+	"github.com/fezcode/atlas.compass/pkg/model"
+)
+
+// ConflictModel walks through the entries that were changed both here and
+// in a copy of the vault written meanwhile, one at a time.
+type ConflictModel struct {
+	Merged    *model.Vault
+	Conflicts []model.Conflict
+	Index     int
+	Err       *store.ConflictError // What Merged is saved with once resolved
+}
+
+// Current returns the conflict being resolved.
+func (m ConflictModel) Current() model.Conflict {
+	return m.Conflicts[m.Index]
+}
+
+// Resolve applies choice to the current conflict and reports whether all
+// of them are resolved.
+func (m *ConflictModel) Resolve(choice model.Choice) bool {
+	m.Merged.Resolve(m.Current(), choice)
+	m.Index++
+	return m.Index >= len(m.Conflicts)
+}
+
+func (m ConflictModel) View() string {
+	c := m.Current()
+	title := StyleAuthHeader.Render(fmt.Sprintf("RESOLVE CONFLICT (%d of %d)", m.Index+1, len(m.Conflicts)))
+
+	name := c.ID
+	if c.Newer() != nil {
+		name = c.Newer().Title
+	}
+	var what string
+	switch {
+	case c.Ours == nil:
+		what = fmt.Sprintf("%q was deleted here but changed in another copy of the vault.", name)
+	case c.Theirs == nil:
+		what = fmt.Sprintf("%q was changed here but deleted in another copy of the vault.", name)
+	default:
+		what = fmt.Sprintf("%q was changed here and in another copy of the vault.", name)
+	}
+
+	var b strings.Builder
+	colWidth := 26
+	row := func(label, ours, theirs string, differs bool) {
+		style := StyleBase
+		if differs {
+			style = lipgloss.NewStyle().Foreground(ColorSecondary)
+		}
+		b.WriteString(StyleEditorLabel.Render(label))
+		b.WriteString(style.Copy().Width(colWidth).Render(truncate(ours, colWidth-2)))
+		b.WriteString(style.Copy().Width(colWidth).Render(truncate(theirs, colWidth-2)))
+		b.WriteString("\n")
+	}
+	b.WriteString(StyleEditorLabel.Render(""))
+	b.WriteString(StyleSubtext.Copy().Width(colWidth).Render("Yours"))
+	b.WriteString(StyleSubtext.Copy().Width(colWidth).Render("On disk"))
+	b.WriteString("\n")
+
+	if c.Ours == nil || c.Theirs == nil {
+		ours, theirs := "deleted", "deleted"
+		if c.Ours != nil {
+			ours = c.Ours.UpdatedAt.Local().Format("2006-01-02 15:04")
+		}
+		if c.Theirs != nil {
+			theirs = c.Theirs.UpdatedAt.Local().Format("2006-01-02 15:04")
+		}
+		row("Updated", ours, theirs, true)
+	} else {
+		o, t := c.Ours, c.Theirs
+		row("Title", o.Title, t.Title, o.Title != t.Title)
+		row("User", o.Username, t.Username, o.Username != t.Username)
+		row("Pass", "••••••", "••••••", o.Password != t.Password)
+		row("URL", o.URL, t.URL, o.URL != t.URL)
+		row("Notes", o.Notes, t.Notes, o.Notes != t.Notes)
+		ot, tt := strings.Join(o.Tags, ", "), strings.Join(t.Tags, ", ")
+		row("Tags", ot, tt, ot != tt)
+		ou, tu := o.UpdatedAt.Local().Format("2006-01-02 15:04"), t.UpdatedAt.Local().Format("2006-01-02 15:04")
+		if c.Newer() == o {
+			ou += " (newer)"
+		} else {
+			tu += " (newer)"
+		}
+		row("Updated", ou, tu, false)
+	}
+
+	keys := "[m] keep mine • [t] keep theirs"
+	if c.Ours != nil && c.Theirs != nil {
+		keys += " • [b] keep both"
+	}
+	content := lipgloss.JoinVertical(lipgloss.Center,
+		title,
+		StyleSubtext.Copy().Width(62).Align(lipgloss.Center).Render(what),
+		"",
+		lipgloss.NewStyle().Align(lipgloss.Left).Render(b.String()),
+		StyleSubtext.Render(keys),
+	)
+	return StyleAuthBox.Render(content)
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,163 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fezcode/atlas.compass/internal/crypto"
+)
+
+// CreateModel is shown on first run, when there is no vault yet. It asks for
+// the master password twice and shows how strong it is as it is typed.
+type CreateModel struct {
+	Inputs    []textinput.Model
+	Focused   NPField
+	AllowWeak bool // User chose to keep a password below crypto.MinStrength
+	Err       error
+	Vault     string   // Name of the vault being created
+	Vaults    []string // Vaults the picker switches between
+}
+
+func NewCreateModel() CreateModel {
+	inputs := make([]textinput.Model, NPFieldCount)
+
+	inputs[NPFieldNew] = textinput.New()
+	inputs[NPFieldNew].Placeholder = "Master Password"
+	inputs[NPFieldNew].EchoMode = textinput.EchoPassword
+	inputs[NPFieldNew].CharLimit = 156
+	inputs[NPFieldNew].Width = 30
+	inputs[NPFieldNew].Focus()
+
+	inputs[NPFieldConfirm] = textinput.New()
+	inputs[NPFieldConfirm].Placeholder = "Confirm Master Password"
+	inputs[NPFieldConfirm].EchoMode = textinput.EchoPassword
+	inputs[NPFieldConfirm].CharLimit = 156
+	inputs[NPFieldConfirm].Width = 30
+
+	return CreateModel{
+		Inputs:  inputs,
+		Focused: NPFieldNew,
+	}
+}
+
+// Values returns the password and its confirmation.
+func (m CreateModel) Values() (string, string) {
+	return m.Inputs[NPFieldNew].Value(), m.Inputs[NPFieldConfirm].Value()
+}
+
+// Strength estimates the strength of the password typed so far.
+func (m CreateModel) Strength() crypto.Strength {
+	return crypto.EstimateStrength(m.Inputs[NPFieldNew].Value())
+}
+
+func (m CreateModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m CreateModel) Update(msg tea.Msg) (CreateModel, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "ctrl+o":
+			m.AllowWeak = !m.AllowWeak
+			m.Err = nil
+			return m, nil
+
+		case "tab", "shift+tab", "enter", "up", "down":
+			s := msg.String()
+
+			// Did user press enter on last field?
+			if s == "enter" && m.Focused == NPFieldCount-1 {
+				return m, nil // Handled by parent
+			}
+
+			if s == "up" || s == "shift+tab" {
+				m.Focused--
+			} else {
+				m.Focused++
+			}
+			if m.Focused > NPFieldCount-1 {
+				m.Focused = 0
+			} else if m.Focused < 0 {
+				m.Focused = NPFieldCount - 1
+			}
+
+			cmds := make([]tea.Cmd, len(m.Inputs))
+			for i := range m.Inputs {
+				if i == int(m.Focused) {
+					cmds[i] = m.Inputs[i].Focus()
+					continue
+				}
+				m.Inputs[i].Blur()
+			}
+			return m, tea.Batch(cmds...)
+		}
+	}
+
+	cmds := make([]tea.Cmd, len(m.Inputs))
+	for i := range m.Inputs {
+		m.Inputs[i], cmds[i] = m.Inputs[i].Update(msg)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+func (m CreateModel) View() string {
+	title := StyleAuthHeader.Render("CREATE YOUR VAULT")
+	hint := StyleSubtext.Render("Choose a master password. It cannot be recovered if you forget it,\nunless you add a recovery key later ([K] in the list).")
+
+	var fields strings.Builder
+	for i, input := range m.Inputs {
+		label := "Master Pass"
+		if NPField(i) == NPFieldConfirm {
+			label = "Retype"
+		}
+		style := StyleEditorLabel.Copy().Width(14)
+		if NPField(i) == m.Focused {
+			style = style.Foreground(ColorPrimary)
+		}
+		fields.WriteString(style.Render(label))
+		fields.WriteString("\n")
+		fields.WriteString(input.View())
+		fields.WriteString("\n\n")
+	}
+
+	meter := strengthMeter(m.Strength())
+	if m.Inputs[NPFieldNew].Value() == "" {
+		meter = StyleSubtext.Render("Strength: -")
+	}
+
+	override := StyleSubtext.Render("[ ] [ctrl+o] use a weak password anyway")
+	if m.AllowWeak {
+		override = lipgloss.NewStyle().Foreground(ColorSecondary).Render("[x] [ctrl+o] use a weak password anyway")
+	}
+
+	errView := ""
+	if m.Err != nil {
+		errView = lipgloss.NewStyle().Foreground(ColorError).MarginTop(1).Render(m.Err.Error())
+	}
+
+	keyText := "[tab] next • [enter] create vault"
+	if len(m.Vaults) > 1 {
+		keyText += " • [ctrl+n/ctrl+p] switch vault"
+	}
+	keys := StyleSubtext.Render(keyText)
+
+	parts := []string{title}
+	if picker := vaultPicker(m.Vaults, m.Vault); picker != "" {
+		parts = append(parts, picker)
+	}
+	parts = append(parts, hint, "", fields.String(), meter, override, errView, "", keys)
+	content := lipgloss.JoinVertical(lipgloss.Center, parts...)
+	return StyleAuthBox.Render(content)
+}
+
+// strengthMeter renders s as a five-step bar with its label.
+func strengthMeter(s crypto.Strength) string {
+	colors := []lipgloss.Color{ColorError, ColorError, ColorCyan, ColorSuccess, ColorSuccess}
+	filled := s.Score + 1
+	bar := strings.Repeat("█", filled*4) + strings.Repeat("░", (crypto.StrengthVeryStrong+1-filled)*4)
+	return lipgloss.NewStyle().Foreground(colors[s.Score]).Render(bar) +
+		StyleSubtext.Render(fmt.Sprintf("  %s (~%.0f bits)", s, s.Bits))
+}
@@ -32,6 +32,9 @@ func (m DetailModel) View() string {
 	renderField("Pass", m.Entry.Password)
 	renderField("URL", m.Entry.URL)
 	renderField("Notes", m.Entry.Notes)
+	if len(m.Entry.Tags) > 0 {
+		renderField("Tags", strings.Join(m.Entry.Tags, ", "))
+	}
 
 	b.WriteString("\n")
 	b.WriteString(StyleSubtext.Render(" [e] Edit • [c] Copy Pass • [u] Copy User • [esc] Back"))
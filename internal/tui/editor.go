@@ -167,6 +167,7 @@ func (m EditorModel) GetEntry() model.Entry {
 	if m.Entry != nil {
 		e.ID = m.Entry.ID
 		e.CreatedAt = m.Entry.CreatedAt
+		e.Tags = m.Entry.Tags
 	}
 	return e
 }
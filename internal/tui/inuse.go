@@ -0,0 +1,43 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fezcode/atlas.compass/internal/store"
+)
+
+// InUseModel is shown instead of the unlock screen when another process
+// holds the vault lock. It offers to open the vault read-only.
+type InUseModel struct {
+	Err       *store.InUseError
+	Vault     string
+	Vaults    []string
+	Refreshed bool // Checked again and still held
+}
+
+func (m InUseModel) View() string {
+	title := StyleAuthHeader.Render("VAULT IN USE")
+
+	who := "Another atlas.compass has this vault open."
+	if h := m.Err.Holder; h != nil {
+		who = fmt.Sprintf("Opened by PID %d on %s\nsince %s.", h.PID, h.Host, h.Since.Local().Format("2006-01-02 15:04"))
+	}
+	detail := StyleSubtext.Copy().Width(50).Align(lipgloss.Center).Render(
+		"Saving here could overwrite its changes. Open read-only to look up entries, or close the other one and try again. If it crashed on another machine, delete " + m.Err.Path + ".")
+
+	parts := []string{title}
+	if picker := vaultPicker(m.Vaults, m.Vault); picker != "" {
+		parts = append(parts, picker)
+	}
+	parts = append(parts, lipgloss.NewStyle().Foreground(ColorSecondary).Render(who), "", detail)
+	if m.Refreshed {
+		parts = append(parts, lipgloss.NewStyle().Foreground(ColorError).MarginTop(1).Render("Still in use."))
+	}
+
+	parts = append(parts, "", StyleSubtext.Render("[r] open read-only • [t] try again • [q] quit"))
+	if len(m.Vaults) > 1 {
+		parts = append(parts, StyleSubtext.Render("[ctrl+n/ctrl+p] switch vault"))
+	}
+	return StyleAuthBox.Render(lipgloss.JoinVertical(lipgloss.Center, parts...))
+}
@@ -73,6 +73,8 @@ func NewListModel(entries []model.Entry, width, height int) ListModel {
 			key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "copy username")),
 			key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "delete")),
 			key.NewBinding(key.WithKeys("P"), key.WithHelp("P", "change master pass")),
+			key.NewBinding(key.WithKeys("K"), key.WithHelp("K", "manage key slots")),
+			key.NewBinding(key.WithKeys("L", "ctrl+l"), key.WithHelp("L", "lock")),
 		}
 	}
 
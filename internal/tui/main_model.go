@@ -1,15 +1,17 @@
 package tui
 
 import (
-	"crypto/rand"
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"time"
 
-	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/fezcode/atlas.compass/internal/agent"
+	"github.com/fezcode/atlas.compass/internal/clipboard"
+	"github.com/fezcode/atlas.compass/internal/config"
+	"github.com/fezcode/atlas.compass/internal/crypto"
 	"github.com/fezcode/atlas.compass/internal/store"
 	"github.com/fezcode/atlas.compass/pkg/model"
 )
@@ -23,6 +25,11 @@ const (
 	StateEditor
 	StateChangePass
 	StateDeleteConfirm
+	StateSlots
+	StateResetPass
+	StateCreate
+	StateInUse
+	StateConflict
 )
 
 type MainModel struct {
@@ -32,23 +39,174 @@ type MainModel struct {
 	Detail         DetailModel
 	Editor         EditorModel
 	ChangePass     ChangePassModel
+	Slots          SlotsModel
+	ResetPass      NewPassModel
+	Create         CreateModel
+	InUse          InUseModel
+	Conflict       ConflictModel
 	Vault          *model.Vault
 	EntryToDelete  *model.Entry
-	MasterPassword string
+	Session        *store.Session // Holds the vault key while unlocked
+	KeyfilePath    string         // Second-factor keyfile, if any
+	KeyfileFlag    string         // --keyfile, which applies to the vault selected at start
+	Vaults         []string       // Vaults the auth screen can switch between
+	StartVault     string
+	ReadOnly       bool // Open without the vault lock, because another process holds it
+	Config         *config.Config
+	KDF            crypto.Params
 	WindowWidth    int
 	WindowHeight   int
 	StatusMsg      string
+	LastActivity   time.Time
+	LockGen        int       // Invalidates idle checks from earlier sessions
+	Clipboard      *clipboard.Guard
+	UseAgent       bool
+	ClipGen        int // Invalidates countdown ticks from earlier copies
+	Restore        *listView // List position to bring back after unlocking
 }
 
-func NewMainModel() MainModel {
-	return MainModel{
-		State: StateAuth,
-		Auth:  NewAuthModel(),
+// listView is the list position kept across a lock.
+type listView struct {
+	EntryID string
+	Filter  string
+}
+
+// Options are command line settings that affect the terminal UI.
+type Options struct {
+	Keyfile   string              // Overrides the keyfile set in the config
+	Clipboard clipboard.Clipboard // Defaults to the system clipboard
+	NoAgent   bool                // Neither use nor feed a running agent
+}
+
+func NewMainModel(cfg *config.Config, opts Options) MainModel {
+	cb := opts.Clipboard
+	if cb == nil {
+		cb = clipboard.System{}
+	}
+	m := MainModel{
+		Config:      cfg,
+		KeyfileFlag: opts.Keyfile,
+		StartVault:  store.Name(),
+		Vaults:      vaultChoices(),
+		Clipboard:   clipboard.NewGuard(cb),
+		UseAgent:    !opts.NoAgent,
 		List:  NewListModel([]model.Entry{}, 0, 0), // Initialize empty list to prevent crash on resize
 	}
+	m.showVault()
+	return m
+}
+
+// vaultChoices lists the vaults in ~/.atlas, plus the selected one if it
+// lives elsewhere or has not been created yet.
+func vaultChoices() []string {
+	names, _ := store.ListVaults()
+	for _, name := range names {
+		if name == store.Name() {
+			return names
+		}
+	}
+	return append(names, store.Name())
+}
+
+// showVault sets up the unlock screen for the selected vault, or the create
+// screen if it does not exist yet. A running agent holding its key skips
+// the unlock screen.
+func (m *MainModel) showVault() {
+	name := store.Name()
+	flag := ""
+	if name == m.StartVault {
+		flag = m.KeyfileFlag
+	}
+	m.KeyfilePath = m.Config.KeyfilePath(name, flag)
+	m.Auth = m.newAuth()
+	m.Create = m.newCreate()
+
+	if !store.Exists() {
+		m.State = StateCreate
+		return
+	}
+	if !m.ReadOnly {
+		if holder, _ := store.LockHolder(); holder != nil {
+			m.showInUse(holder)
+			return
+		}
+	}
+	m.State = StateAuth
+	if !m.UseAgent {
+		return
+	}
+	if vault, session, err := agent.Open(m.mode()); err == nil {
+		m.Vault = vault
+		m.Session = session
+		m.KDF = session.Params().Raise(m.Config.KDFParams(name))
+		m.State = StateList
+		m.List = NewListModel(vault.Entries, m.WindowWidth, m.WindowHeight-4)
+		m.StatusMsg = "Unlocked by the agent."
+		m.LockGen++
+		m.LastActivity = time.Now()
+	}
+}
+
+// showInUse tells the user another process holds the vault lock.
+func (m *MainModel) showInUse(holder *store.InUseError) {
+	m.State = StateInUse
+	m.InUse = InUseModel{Err: holder, Vault: store.Name(), Vaults: m.Vaults}
+}
+
+// mode is the store.Mode to unlock the selected vault in.
+func (m MainModel) mode() store.Mode {
+	if m.ReadOnly {
+		return store.ReadOnly
+	}
+	return store.ReadWrite
+}
+
+// switchVault selects the vault step places away in the picker.
+func (m *MainModel) switchVault(step int) tea.Cmd {
+	if len(m.Vaults) < 2 {
+		return nil
+	}
+	i := 0
+	for j, name := range m.Vaults {
+		if name == store.Name() {
+			i = j
+		}
+	}
+	i = (i + step + len(m.Vaults)) % len(m.Vaults)
+	if err := store.Select(m.Vaults[i]); err != nil {
+		m.Auth.Err = err
+		return nil
+	}
+	m.Restore = nil // List positions belong to the vault they came from
+	m.ReadOnly = false
+	m.showVault()
+	return m.Init()
+}
+
+func (m MainModel) newAuth() AuthModel {
+	a := NewAuthModel()
+	a.Vault, a.Vaults = store.Name(), m.Vaults
+	a.ReadOnly = m.ReadOnly
+	return a
+}
+
+func (m MainModel) newCreate() CreateModel {
+	c := NewCreateModel()
+	c.Vault, c.Vaults = store.Name(), m.Vaults
+	return c
 }
 
 func (m MainModel) Init() tea.Cmd {
+	switch {
+	case m.Session != nil: // Unlocked by the agent
+		cmds := []tea.Cmd{m.clearStatusAfter(3 * time.Second)}
+		if timeout := m.Config.AutoLockAfter(); timeout > 0 {
+			cmds = append(cmds, m.idleCheckAfter(timeout))
+		}
+		return tea.Batch(cmds...)
+	case m.State == StateCreate:
+		return m.Create.Init()
+	}
 	return m.Auth.Init()
 }
 
@@ -63,45 +221,171 @@ func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.List.List.SetSize(msg.Width, msg.Height-4) // Reserve space for header/status
 		
 	case tea.KeyMsg:
+		m.LastActivity = time.Now()
 		switch msg.String() {
 		case "ctrl+c":
-			return m, tea.Quit
+			return m, m.quit()
+		case "ctrl+l":
+			if m.Session != nil {
+				return m, m.lock("Vault locked.")
+			}
+		}
+
+	case tea.MouseMsg:
+		m.LastActivity = time.Now()
+
+	case clipboardTickMsg:
+		if msg.gen != m.ClipGen || !m.Clipboard.Pending() {
+			return m, nil
+		}
+		if m.Clipboard.Expired() {
+			m.clearClipboard()
+			return m, m.clearStatusAfter(2 * time.Second)
+		}
+		return m, m.clipboardTick()
+
+	case idleCheckMsg:
+		if msg.gen != m.LockGen || m.Session == nil {
+			return m, nil // Stale: the vault was locked or unlocked since
+		}
+		timeout := m.Config.AutoLockAfter()
+		idle := time.Since(m.LastActivity)
+		if idle >= timeout {
+			return m, m.lock(fmt.Sprintf("Locked after %s of inactivity.", timeout))
+		}
+		return m, m.idleCheckAfter(timeout - idle)
+	}
+
+	// Either screen before unlocking can switch to another vault
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && (m.State == StateAuth || m.State == StateCreate || m.State == StateInUse) {
+		switch keyMsg.String() {
+		case "ctrl+n":
+			return m, m.switchVault(1)
+		case "ctrl+p":
+			return m, m.switchVault(-1)
 		}
 	}
 
 	switch m.State {
+	case StateInUse:
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			switch keyMsg.String() {
+			case "r":
+				m.ReadOnly = true
+				m.showVault()
+				return m, m.Init()
+			case "t":
+				m.showVault()
+				if m.State == StateInUse {
+					m.InUse.Refreshed = true
+				}
+				return m, m.Init()
+			case "q", "esc":
+				return m, m.quit()
+			}
+		}
+		return m, nil
+
+	case StateCreate:
+		if keyMsg, ok := msg.(tea.KeyMsg); ok && keyMsg.Type == tea.KeyEnter && m.Create.Focused == NPFieldCount-1 {
+			return m, m.createVault()
+		}
+
+		var createCmd tea.Cmd
+		m.Create, createCmd = m.Create.Update(msg)
+		cmds = append(cmds, createCmd)
+
 	case StateAuth:
 		// Handle Auth Logic
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
 			if msg.Type == tea.KeyEnter {
 				// Try to unlock
-				pass := m.Auth.Input.Value()
-				if pass == "" {
-					m.Auth.Err = fmt.Errorf("password cannot be empty")
+				m.Auth.Hint = ""
+				value := m.Auth.Input.Value()
+				if value == "" {
+					m.Auth.Err = fmt.Errorf("input cannot be empty")
 					return m, nil
 				}
-				
-				vault, err := store.Load(pass)
-				if err != nil {
-					// Check if it's a decryption error vs file error
-					// For now assume decryption error if file exists
-					if store.Exists() {
-						m.Auth.Err = fmt.Errorf("invalid password")
-						m.Auth.Input.SetValue("")
+
+				// The vault is gone (e.g. deleted while we were running): never
+				// let a single unconfirmed entry become the master password
+				if !store.Exists() {
+					m.State = StateCreate
+					m.Create = m.newCreate()
+					return m, m.Create.Init()
+				}
+
+				cred := crypto.PasswordCredential(value)
+				switch m.Auth.Mode {
+				case AuthPassword:
+					var err error
+					if cred, err = m.withKeyfile(cred); err != nil {
+						m.Auth.Err = err
+						return m, nil
+					}
+				case AuthKeyfile:
+					var err error
+					if cred, err = crypto.KeyfileCredential(value); err != nil {
+						m.Auth.Err = fmt.Errorf("cannot read keyfile: %w", err)
 						return m, nil
 					}
-					// New vault logic could go here, but Load returns empty vault if not exists
-					// So actually if err != nil here it's a real error (corruption/permission)
+				case AuthRecovery:
+					key, err := crypto.DecodeMnemonic(value)
+					if err != nil {
+						m.Auth.Err = err
+						return m, nil
+					}
+					cred = crypto.RecoveryCredential(key)
+				}
+
+				m.Auth.Input.SetValue("")
+				vault, session, err := store.Load(cred, m.mode())
+				var inUse *store.InUseError
+				if errors.As(err, &inUse) {
+					cred.Wipe()
+					m.showInUse(inUse) // Taken since the screen was shown
+					return m, nil
+				}
+				if err != nil {
+					cred.Wipe()
 					m.Auth.Err = err
+					m.Auth.Hint = store.Hint(err)
+					if !errors.Is(err, crypto.ErrBadCredentials) {
+						m.Auth.Input.SetValue(value) // Nothing wrong with what was typed
+					}
 					return m, nil
 				}
 
 				m.Vault = vault
-				m.MasterPassword = pass
+				m.Session = session
 				m.State = StateList
 				m.List = NewListModel(vault.Entries, m.WindowWidth, m.WindowHeight-4)
-				return m, nil
+				m.restoreListView()
+				m.upgradeKDF(cred)
+				cred.Wipe()
+				m.applyCipher()
+				if m.UseAgent && session.Kind() != crypto.SlotRecovery {
+					agent.AddSession(session) // Fails harmlessly if no agent is running
+				}
+				idleCmd := m.startIdleTimer()
+
+				// A recovery key means the master password was lost: set a new one
+				// first, unless the vault is read-only and no password can be set
+				if session.Kind() == crypto.SlotRecovery && session.ReadOnly() {
+					m.StatusMsg = "Read-only: to set a new master password, lock and unlock again once the other atlas.compass closes."
+					return m, tea.Batch(m.clearStatusAfter(8*time.Second), idleCmd)
+				}
+				if session.Kind() == crypto.SlotRecovery {
+					m.State = StateResetPass
+					m.ResetPass = NewNewPassModel("Set New Master Password",
+						"Unlocked with your recovery key. Choose a new master password;\nit replaces all existing password slots.")
+					return m, tea.Batch(m.ResetPass.Init(), idleCmd)
+				}
+				if m.StatusMsg != "" {
+					return m, tea.Batch(m.clearStatusAfter(3*time.Second), idleCmd)
+				}
+				return m, idleCmd
 			}
 		}
 		
@@ -117,13 +401,21 @@ func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.List.List.FilterState() == list.Filtering {
 				break
 			}
+			if m.Session.ReadOnly() {
+				switch msg.String() {
+				case "a", "e", "d", "P", "K":
+					return m, m.refuseReadOnly()
+				}
+			}
 
 			switch msg.String() {
 			case "?":
 				m.List.List.SetShowHelp(!m.List.List.ShowHelp())
 				return m, nil
 			case "q":
-				return m, tea.Quit
+				return m, m.quit()
+			case "L":
+				return m, m.lock("Vault locked.")
 			case "a":
 				m.State = StateEditor
 				m.Editor = NewEditorModel()
@@ -132,6 +424,15 @@ func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.State = StateChangePass
 				m.ChangePass = NewChangePassModel()
 				return m, m.ChangePass.Init()
+			case "K":
+				slots, err := store.ListSlots()
+				if err != nil {
+					m.StatusMsg = "Error reading key slots: " + err.Error()
+					return m, m.clearStatusAfter(3 * time.Second)
+				}
+				m.State = StateSlots
+				m.Slots = NewSlotsModel(slots)
+				return m, nil
 			case "enter":
 				// View details
 				if item, ok := m.List.List.SelectedItem().(item); ok {
@@ -150,16 +451,12 @@ func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "c":
 				// Copy Password
 				if item, ok := m.List.List.SelectedItem().(item); ok {
-					clipboard.WriteAll(item.entry.Password)
-					m.StatusMsg = "Password copied to clipboard!"
-					return m, m.clearStatusAfter(2 * time.Second)
+					return m, m.copyToClipboard(item.entry.Password, "Password copied to clipboard!")
 				}
 			case "u":
 				// Copy Username
 				if item, ok := m.List.List.SelectedItem().(item); ok {
-					clipboard.WriteAll(item.entry.Username)
-					m.StatusMsg = "Username copied to clipboard!"
-					return m, m.clearStatusAfter(2 * time.Second)
+					return m, m.copyToClipboard(item.entry.Username, "Username copied to clipboard!")
 				}
 			case "d":
 				// Trigger Delete Confirmation
@@ -183,18 +480,17 @@ func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.State = StateList
 				return m, nil
 			case "e":
+				if m.Session.ReadOnly() {
+					return m, m.refuseReadOnly()
+				}
 				m.State = StateEditor
 				m.Editor = NewEditorModel()
 				m.Editor.SetEntry(m.Detail.Entry)
 				return m, m.Editor.Init()
 			case "c":
-				clipboard.WriteAll(m.Detail.Entry.Password)
-				m.StatusMsg = "Password copied!"
-				return m, m.clearStatusAfter(2 * time.Second)
+				return m, m.copyToClipboard(m.Detail.Entry.Password, "Password copied!")
 			case "u":
-				clipboard.WriteAll(m.Detail.Entry.Username)
-				m.StatusMsg = "Username copied!"
-				return m, m.clearStatusAfter(2 * time.Second)
+				return m, m.copyToClipboard(m.Detail.Entry.Username, "Username copied!")
 			}
 		}
 
@@ -216,7 +512,7 @@ func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 				if newEntry.ID == "" {
 					// Create new
-					newEntry.ID = generateID()
+					newEntry.ID = model.NewID()
 					newEntry.CreatedAt = time.Now()
 					newEntry.UpdatedAt = time.Now()
 					m.Vault.Entries = append(m.Vault.Entries, newEntry)
@@ -230,11 +526,7 @@ func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						}
 					}
 				}
-				m.saveVault()
-				m.refreshList()
-				m.State = StateList
-				m.StatusMsg = "Entry saved."
-				return m, m.clearStatusAfter(2 * time.Second)
+				return m, m.saveVault("Entry saved.")
 			}
 		}
 
@@ -260,10 +552,6 @@ func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.StatusMsg = "Error: You must type YES to confirm."
 					return m, m.clearStatusAfter(3 * time.Second)
 				}
-				if current != m.MasterPassword {
-					m.StatusMsg = "Error: Current password incorrect."
-					return m, m.clearStatusAfter(3 * time.Second)
-				}
 				if newPass == "" {
 					m.StatusMsg = "Error: New password cannot be empty."
 					return m, m.clearStatusAfter(3 * time.Second)
@@ -273,14 +561,21 @@ func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return m, m.clearStatusAfter(3 * time.Second)
 				}
 
-				// Perform Re-encryption
-				if err := store.Save(m.Vault, newPass); err != nil {
-					m.StatusMsg = "CRITICAL ERROR: Failed to save vault: " + err.Error()
-					return m, nil
+				// Rewrap the data key under the new password
+				currentCred, err := m.withKeyfile(crypto.PasswordCredential(current))
+				if err != nil {
+					m.StatusMsg = "Error: " + err.Error()
+					return m, m.clearStatusAfter(3 * time.Second)
+				}
+				newCred, _ := m.withKeyfile(crypto.PasswordCredential(newPass))
+				err = m.Session.ChangePassword(currentCred, newCred, m.KDF)
+				currentCred.Wipe()
+				newCred.Wipe()
+				if err != nil {
+					m.StatusMsg = "Error: Could not change password: " + err.Error()
+					return m, m.clearStatusAfter(3 * time.Second)
 				}
 
-				// Success! Update memory
-				m.MasterPassword = newPass
 				m.State = StateList
 				m.StatusMsg = "Success! Master Password Changed."
 				return m, m.clearStatusAfter(3 * time.Second)
@@ -291,6 +586,120 @@ func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.ChangePass, cpCmd = m.ChangePass.Update(msg)
 		cmds = append(cmds, cpCmd)
 
+	case StateSlots:
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			switch m.Slots.Mode {
+			case SlotsBrowse:
+				switch keyMsg.String() {
+				case "esc", "backspace":
+					m.State = StateList
+					return m, nil
+				case "a":
+					return m, m.Slots.StartAddPassword()
+				case "f":
+					return m, m.Slots.StartAddKeyfile()
+				case "r":
+					return m, m.addRecoverySlot()
+				case "d":
+					if len(m.Slots.Slots) <= 1 {
+						m.StatusMsg = "Error: A vault must keep at least one key slot."
+						return m, m.clearStatusAfter(3 * time.Second)
+					}
+					if _, ok := m.Slots.Selected(); ok {
+						m.Slots.Mode = SlotsConfirmRemove
+					}
+					return m, nil
+				}
+
+			case SlotsAddPassword, SlotsAddKeyfile:
+				if keyMsg.Type == tea.KeyEsc {
+					m.Slots.Browse()
+					return m, nil
+				}
+				if keyMsg.Type == tea.KeyEnter && m.Slots.OnLastField() {
+					return m, m.addSlot()
+				}
+
+			case SlotsConfirmRemove:
+				switch keyMsg.String() {
+				case "y", "Y":
+					return m, m.removeSlot()
+				case "n", "N", "esc":
+					m.Slots.Browse()
+					return m, nil
+				}
+
+			case SlotsShowRecovery:
+				if keyMsg.Type == tea.KeyEnter {
+					m.reloadSlots()
+					return m, nil
+				}
+				return m, nil
+			}
+		}
+
+		var slotsCmd tea.Cmd
+		m.Slots, slotsCmd = m.Slots.Update(msg)
+		cmds = append(cmds, slotsCmd)
+
+	case StateResetPass:
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			if keyMsg.Type == tea.KeyEnter && m.ResetPass.Focused == NPFieldCount-1 {
+				newPass, confirm := m.ResetPass.Values()
+				if newPass == "" {
+					m.StatusMsg = "Error: New password cannot be empty."
+					return m, m.clearStatusAfter(3 * time.Second)
+				}
+				if newPass != confirm {
+					m.StatusMsg = "Error: New passwords do not match."
+					return m, m.clearStatusAfter(3 * time.Second)
+				}
+
+				newCred, err := m.withKeyfile(crypto.PasswordCredential(newPass))
+				if err != nil {
+					m.StatusMsg = "Error: " + err.Error()
+					return m, m.clearStatusAfter(3 * time.Second)
+				}
+				err = m.Session.ResetPassword(newCred, m.KDF)
+				newCred.Wipe()
+				if err != nil {
+					m.StatusMsg = "Error: Could not set password: " + err.Error()
+					return m, m.clearStatusAfter(3 * time.Second)
+				}
+
+				m.State = StateList
+				m.StatusMsg = "Success! New Master Password set."
+				return m, m.clearStatusAfter(3 * time.Second)
+			}
+		}
+
+		var rpCmd tea.Cmd
+		m.ResetPass, rpCmd = m.ResetPass.Update(msg)
+		cmds = append(cmds, rpCmd)
+
+	case StateConflict:
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			choice := model.Choice(-1)
+			switch keyMsg.String() {
+			case "m":
+				choice = model.KeepOurs
+			case "t":
+				choice = model.KeepTheirs
+			case "b":
+				if c := m.Conflict.Current(); c.Ours != nil && c.Theirs != nil {
+					choice = model.KeepBoth
+				}
+			}
+			if choice < 0 || !m.Conflict.Resolve(choice) {
+				return m, nil
+			}
+			m.Vault = m.Conflict.Merged
+			err := m.Session.SaveResolved(m.Vault, m.Conflict.Err)
+			m.Conflict = ConflictModel{}
+			return m, m.afterSave(err, "Merged with the changes on disk.")
+		}
+		return m, nil
+
 	case StateDeleteConfirm:
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
@@ -298,12 +707,8 @@ func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "y", "Y":
 				if m.EntryToDelete != nil {
 					m.deleteEntry(m.EntryToDelete.ID)
-					m.saveVault()
-					m.refreshList()
-					m.StatusMsg = "Entry deleted."
-					m.State = StateList
 					m.EntryToDelete = nil
-					return m, m.clearStatusAfter(2 * time.Second)
+					return m, m.saveVault("Entry deleted.")
 				}
 			case "n", "N", "esc":
 				m.State = StateList
@@ -324,6 +729,24 @@ func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m MainModel) View() string {
 	switch m.State {
+	case StateCreate:
+		return lipgloss.Place(
+			m.WindowWidth, m.WindowHeight,
+			lipgloss.Center, lipgloss.Center,
+			m.Create.View(),
+		)
+	case StateConflict:
+		return lipgloss.Place(
+			m.WindowWidth, m.WindowHeight,
+			lipgloss.Center, lipgloss.Center,
+			m.Conflict.View(),
+		)
+	case StateInUse:
+		return lipgloss.Place(
+			m.WindowWidth, m.WindowHeight,
+			lipgloss.Center, lipgloss.Center,
+			m.InUse.View(),
+		)
 	case StateAuth:
 		// Center auth box
 		return lipgloss.Place(
@@ -332,20 +755,32 @@ func (m MainModel) View() string {
 			m.Auth.View(),
 		)
 	case StateList:
+		// m is a copy here
+		if name := store.Name(); name != store.DefaultName {
+			m.List.List.Title = "Compass Vault · " + name
+		}
+		if m.Session.ReadOnly() {
+			m.List.List.Title += " (read-only)"
+		}
 		view := m.List.View()
-		helpHint := StyleSubtext.Render(" [a] add • [enter] view • [e] edit • [c] copy pass • [u] copy user • [d] delete • [P] pass • [q] quit • [?] help")
-		if m.StatusMsg != "" {
-			status := StyleStatusBar.Render("❯ " + m.StatusMsg)
+		helpHint := StyleSubtext.Render(" [a] add • [enter] view • [e] edit • [c] copy pass • [u] copy user • [d] delete • [P] pass • [K] keys • [L] lock • [q] quit • [?] help")
+		if text := m.statusText(); text != "" {
+			status := StyleStatusBar.Render("❯ " + text)
 			view = lipgloss.JoinVertical(lipgloss.Left, view, status, helpHint)
 		} else {
 			view = lipgloss.JoinVertical(lipgloss.Left, view, "", helpHint)
 		}
 		return view
 	case StateDetail:
+		content := m.Detail.View()
+		if text := m.statusText(); text != "" {
+			status := StyleStatusBar.Render("❯ " + text)
+			content = lipgloss.JoinVertical(lipgloss.Left, content, "", status)
+		}
 		return lipgloss.Place(
 			m.WindowWidth, m.WindowHeight,
 			lipgloss.Center, lipgloss.Center,
-			m.Detail.View(),
+			content,
 		)
 	case StateEditor:
 		content := m.Editor.View()
@@ -369,6 +804,28 @@ func (m MainModel) View() string {
 			lipgloss.Center, lipgloss.Center,
 			content,
 		)
+	case StateResetPass:
+		content := m.ResetPass.View()
+		if m.StatusMsg != "" {
+			status := StyleStatusBar.Render("❯ " + m.StatusMsg)
+			content = lipgloss.JoinVertical(lipgloss.Left, content, "", status)
+		}
+		return lipgloss.Place(
+			m.WindowWidth, m.WindowHeight,
+			lipgloss.Center, lipgloss.Center,
+			content,
+		)
+	case StateSlots:
+		content := m.Slots.View()
+		if m.StatusMsg != "" {
+			status := StyleStatusBar.Render("❯ " + m.StatusMsg)
+			content = lipgloss.JoinVertical(lipgloss.Left, content, "", status)
+		}
+		return lipgloss.Place(
+			m.WindowWidth, m.WindowHeight,
+			lipgloss.Center, lipgloss.Center,
+			content,
+		)
 	case StateDeleteConfirm:
 		title := StyleAuthHeader.Render("CONFIRM DELETE")
 		msg := fmt.Sprintf("Are you sure you want to delete\n\"%s\"?", m.EntryToDelete.Title)
@@ -387,10 +844,219 @@ func (m MainModel) View() string {
 
 // Helpers
 
-func (m *MainModel) saveVault() {
-	if err := store.Save(m.Vault, m.MasterPassword); err != nil {
+// createVault writes a new, empty vault protected by the password from the
+// create screen, once it is confirmed and strong enough (or overridden).
+func (m *MainModel) createVault() tea.Cmd {
+	pass, confirm := m.Create.Values()
+	if pass == "" {
+		m.Create.Err = fmt.Errorf("password cannot be empty")
+		return nil
+	}
+	if pass != confirm {
+		m.Create.Err = fmt.Errorf("passwords do not match")
+		m.Create.Inputs[NPFieldConfirm].SetValue("")
+		return nil
+	}
+	if s := m.Create.Strength(); s.Weak() && !m.Create.AllowWeak {
+		m.Create.Err = fmt.Errorf("password is %s: make it longer or mix character types (or ctrl+o to override)", s)
+		return nil
+	}
+
+	cred, err := m.withKeyfile(crypto.PasswordCredential(pass))
+	if err != nil {
+		m.Create.Err = err
+		return nil
+	}
+
+	vault := &model.Vault{Entries: []model.Entry{}}
+	kdf := m.Config.KDFParams(store.Name())
+	session, err := store.Create(vault, cred, kdf)
+	cred.Wipe()
+	if err != nil {
+		m.Create.Err = err
+		return nil
+	}
+	m.Create = m.newCreate()
+
+	m.Vault = vault
+	m.Session = session
+	m.KDF = kdf
+	m.State = StateList
+	m.List = NewListModel(vault.Entries, m.WindowWidth, m.WindowHeight-4)
+	m.StatusMsg = "Vault created. Press [K] to add a recovery key."
+	m.applyCipher()
+	return tea.Batch(m.clearStatusAfter(5*time.Second), m.startIdleTimer())
+}
+
+// refuseReadOnly explains why a change cannot be made.
+func (m *MainModel) refuseReadOnly() tea.Cmd {
+	m.StatusMsg = "Read-only: another atlas.compass holds the vault lock."
+	return m.clearStatusAfter(3 * time.Second)
+}
+
+// saveVault writes the vault and returns to the list showing done. Changes
+// made meanwhile in another copy of the file are merged in; if they clash
+// with ours, it switches to the conflict screen instead, and nothing is
+// saved until they are resolved.
+func (m *MainModel) saveVault(done string) tea.Cmd {
+	return m.afterSave(m.Session.Save(m.Vault), done)
+}
+
+// afterSave handles the result of saving the vault like saveVault.
+func (m *MainModel) afterSave(err error, done string) tea.Cmd {
+	var conflict *store.ConflictError
+	if errors.As(err, &conflict) {
+		m.State = StateConflict
+		m.Conflict = ConflictModel{Merged: conflict.Merged, Conflicts: conflict.Conflicts, Err: conflict}
+		return nil
+	}
+	m.refreshList()
+	m.State = StateList
+	if err != nil {
 		m.StatusMsg = "Error saving vault: " + err.Error()
+		return m.clearStatusAfter(5 * time.Second)
 	}
+	m.StatusMsg = done
+	return m.clearStatusAfter(2 * time.Second)
+}
+
+// withKeyfile adds the configured keyfile, if any, to a password credential.
+func (m *MainModel) withKeyfile(cred crypto.Credential) (crypto.Credential, error) {
+	if m.KeyfilePath == "" {
+		return cred, nil
+	}
+	digest, err := crypto.ReadKeyfile(m.KeyfilePath)
+	if err != nil {
+		return cred, fmt.Errorf("cannot read keyfile: %w", err)
+	}
+	return cred.WithKeyfile(digest), nil
+}
+
+// applyCipher re-encrypts the vault if the config asks for a different
+// payload cipher than the file uses.
+func (m *MainModel) applyCipher() {
+	want := m.Config.Cipher(store.Name())
+	if want == nil || m.Session.ReadOnly() || !store.Exists() {
+		return
+	}
+	have, err := store.Cipher()
+	if err != nil || have.ID() == want.ID() {
+		return
+	}
+	if err := m.Session.SetCipher(want); err != nil {
+		m.StatusMsg = "Error switching cipher: " + err.Error()
+		return
+	}
+	m.StatusMsg = "Vault re-encrypted with " + want.String() + "."
+}
+
+// upgradeKDF picks the key derivation parameters for this session from the
+// ones the vault was unlocked with. If they are below the configured
+// minimum, the slot cred opened is rewrapped straight away, and the outcome
+// is left in the status bar.
+func (m *MainModel) upgradeKDF(cred crypto.Credential) {
+	min := m.Config.KDFParams(store.Name())
+	stored := m.Session.Params()
+	m.KDF = stored
+	if !stored.Below(min) || m.Session.ReadOnly() {
+		return
+	}
+
+	m.KDF = stored.Raise(min)
+	if err := m.Session.Rewrap(cred, m.KDF); err != nil {
+		m.KDF = stored
+		m.StatusMsg = "Error upgrading key derivation: " + err.Error()
+		return
+	}
+	m.StatusMsg = "Vault re-encrypted with stronger key derivation (" + m.KDF.String() + ")."
+}
+
+// addSlot adds the key slot described by the slots form.
+func (m *MainModel) addSlot() tea.Cmd {
+	var newCred crypto.Credential
+	switch m.Slots.Mode {
+	case SlotsAddPassword:
+		pass := m.Slots.Inputs[0].Value()
+		if pass == "" {
+			m.StatusMsg = "Error: Password cannot be empty."
+			return m.clearStatusAfter(3 * time.Second)
+		}
+		if pass != m.Slots.Inputs[1].Value() {
+			m.StatusMsg = "Error: Passwords do not match."
+			return m.clearStatusAfter(3 * time.Second)
+		}
+		var err error
+		if newCred, err = m.withKeyfile(crypto.PasswordCredential(pass)); err != nil {
+			m.StatusMsg = "Error: " + err.Error()
+			return m.clearStatusAfter(3 * time.Second)
+		}
+	case SlotsAddKeyfile:
+		var err error
+		if newCred, err = crypto.KeyfileCredential(m.Slots.Inputs[0].Value()); err != nil {
+			m.StatusMsg = "Error: Cannot read keyfile: " + err.Error()
+			return m.clearStatusAfter(3 * time.Second)
+		}
+	}
+
+	err := m.Session.AddSlot(newCred, m.KDF)
+	newCred.Wipe()
+	if err != nil {
+		m.StatusMsg = "Error adding key slot: " + err.Error()
+		return m.clearStatusAfter(3 * time.Second)
+	}
+	m.reloadSlots()
+	m.StatusMsg = "Added " + newCred.Kind.String() + " slot."
+	return m.clearStatusAfter(3 * time.Second)
+}
+
+// addRecoverySlot generates a recovery key, adds a slot for it and shows
+// its words. They are never stored and cannot be shown again.
+func (m *MainModel) addRecoverySlot() tea.Cmd {
+	key, err := crypto.NewRecoveryKey()
+	if err != nil {
+		m.StatusMsg = "Error generating recovery key: " + err.Error()
+		return m.clearStatusAfter(3 * time.Second)
+	}
+
+	defer crypto.Wipe(key)
+
+	if err := m.Session.AddSlot(crypto.RecoveryCredential(key), m.KDF); err != nil {
+		m.StatusMsg = "Error adding key slot: " + err.Error()
+		return m.clearStatusAfter(3 * time.Second)
+	}
+	m.Slots.ShowRecovery(crypto.EncodeMnemonic(key))
+	return nil
+}
+
+// removeSlot removes the slot under the cursor of the slots screen.
+func (m *MainModel) removeSlot() tea.Cmd {
+	slot, ok := m.Slots.Selected()
+	if !ok {
+		m.Slots.Browse()
+		return nil
+	}
+
+	if err := m.Session.RemoveSlot(slot.Index); err != nil {
+		m.Slots.Browse()
+		m.StatusMsg = "Error removing key slot: " + err.Error()
+		return m.clearStatusAfter(3 * time.Second)
+	}
+	m.reloadSlots()
+	m.StatusMsg = fmt.Sprintf("Removed slot #%d.", slot.Index)
+	return m.clearStatusAfter(3 * time.Second)
+}
+
+func (m *MainModel) reloadSlots() {
+	cursor := m.Slots.Cursor
+	slots, err := store.ListSlots()
+	if err != nil {
+		slots = nil
+	}
+	m.Slots = NewSlotsModel(slots)
+	if cursor >= len(slots) {
+		cursor = len(slots) - 1
+	}
+	m.Slots.Cursor = cursor
 }
 
 func (m *MainModel) refreshList() {
@@ -408,13 +1074,146 @@ func (m *MainModel) deleteEntry(id string) {
 	m.Vault.Entries = newEntries
 }
 
-func generateID() string {
-	b := make([]byte, 8)
-	rand.Read(b)
-	return hex.EncodeToString(b)
+// Status clearing
+// idleCheckMsg fires when the vault may have been idle for the auto-lock
+// timeout. gen ties it to the unlock that scheduled it.
+type idleCheckMsg struct{ gen int }
+
+// startIdleTimer starts the auto-lock countdown for a freshly unlocked vault.
+func (m *MainModel) startIdleTimer() tea.Cmd {
+	m.LockGen++
+	m.LastActivity = time.Now()
+	timeout := m.Config.AutoLockAfter()
+	if timeout <= 0 {
+		return nil
+	}
+	return m.idleCheckAfter(timeout)
+}
+
+func (m MainModel) idleCheckAfter(d time.Duration) tea.Cmd {
+	gen := m.LockGen
+	return tea.Tick(d, func(_ time.Time) tea.Msg {
+		return idleCheckMsg{gen: gen}
+	})
+}
+
+// lock wipes the vault key, drops every decrypted entry and returns to the
+// unlock screen, showing reason. The list position is kept so unlocking
+// again brings the user back to where they were.
+func (m *MainModel) lock(reason string) tea.Cmd {
+	view := &listView{Filter: m.List.List.FilterValue()}
+	if it, ok := m.List.List.SelectedItem().(item); ok {
+		view.EntryID = it.entry.ID
+	}
+	m.Restore = view
+
+	m.clearClipboard()
+	if m.UseAgent {
+		// Otherwise the agent would unlock it again for anyone; the keys of
+		// other vaults are left to the instances using them
+		agent.LockVault(m.Session.Path())
+	}
+	m.Session.Close()
+	m.Session = nil
+	m.Vault = nil
+	m.EntryToDelete = nil
+	m.Conflict = ConflictModel{}
+	m.Detail = DetailModel{}
+	m.Editor = EditorModel{}
+	m.ChangePass = ChangePassModel{}
+	m.Slots = SlotsModel{}
+	m.ResetPass = NewPassModel{}
+	m.List = NewListModel([]model.Entry{}, m.WindowWidth, m.WindowHeight-4)
+	m.StatusMsg = ""
+	m.LockGen++
+
+	if m.ReadOnly {
+		if holder, _ := store.LockHolder(); holder == nil {
+			m.ReadOnly = false // The other instance is done with it
+		}
+	}
+	m.State = StateAuth
+	m.Auth = m.newAuth()
+	m.Auth.Notice = reason
+	return m.Auth.Init()
+}
+
+// restoreListView brings back the list position saved by lock.
+func (m *MainModel) restoreListView() {
+	v := m.Restore
+	m.Restore = nil
+	if v == nil {
+		return
+	}
+	if v.Filter != "" {
+		m.List.List.SetFilterText(v.Filter)
+	}
+	for i, it := range m.List.List.VisibleItems() {
+		if it.(item).entry.ID == v.EntryID {
+			m.List.List.Select(i)
+			break
+		}
+	}
+}
+
+// clipboardTickMsg updates the clipboard countdown once a second. gen ties
+// it to the copy that scheduled it.
+type clipboardTickMsg struct{ gen int }
+
+// copyToClipboard copies text and starts the countdown to clear it again.
+func (m *MainModel) copyToClipboard(text, done string) tea.Cmd {
+	if err := m.Clipboard.Copy(text, m.Config.ClipboardClearAfter()); err != nil {
+		m.StatusMsg = "Error copying to clipboard: " + err.Error()
+		return m.clearStatusAfter(3 * time.Second)
+	}
+	m.ClipGen++
+	m.StatusMsg = done
+	return tea.Batch(m.clearStatusAfter(2*time.Second), m.clipboardTick())
+}
+
+func (m MainModel) clipboardTick() tea.Cmd {
+	left := m.Clipboard.Remaining()
+	if left <= 0 {
+		return nil // Cleared only on quit or lock
+	}
+	gen := m.ClipGen
+	return tea.Tick(min(left, time.Second), func(_ time.Time) tea.Msg {
+		return clipboardTickMsg{gen: gen}
+	})
+}
+
+// clearClipboard takes a copied value off the clipboard, unless the user
+// has copied something else since.
+func (m *MainModel) clearClipboard() {
+	cleared, err := m.Clipboard.Clear()
+	switch {
+	case err != nil:
+		m.StatusMsg = "Error clearing clipboard: " + err.Error()
+	case cleared:
+		m.StatusMsg = "Clipboard cleared."
+	}
+}
+
+// statusText is the status bar message followed by the clipboard countdown.
+func (m MainModel) statusText() string {
+	text := m.StatusMsg
+	if left := m.Clipboard.Remaining(); left > 0 {
+		countdown := fmt.Sprintf("clipboard clears in %ds", int((left+time.Second-1)/time.Second))
+		if text != "" {
+			text += " • "
+		}
+		text += countdown
+	}
+	return text
+}
+
+// quit wipes the vault key and the clipboard before exiting.
+func (m *MainModel) quit() tea.Cmd {
+	m.Clipboard.Clear()
+	m.Session.Close()
+	return tea.Quit
 }
 
-// Status clearing
 type clearStatusMsg struct{}
 
 func (m MainModel) clearStatusAfter(d time.Duration) tea.Cmd {
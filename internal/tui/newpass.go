@@ -0,0 +1,134 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type NPField int
+
+const (
+	NPFieldNew NPField = iota
+	NPFieldConfirm
+	NPFieldCount
+)
+
+// NewPassModel asks for a new master password twice. It is used when the
+// old password cannot be given, e.g. after unlocking with a recovery key.
+type NewPassModel struct {
+	Inputs  []textinput.Model
+	Focused NPField
+	Title   string
+	Hint    string
+}
+
+func NewNewPassModel(title, hint string) NewPassModel {
+	inputs := make([]textinput.Model, NPFieldCount)
+
+	inputs[NPFieldNew] = textinput.New()
+	inputs[NPFieldNew].Placeholder = "New Password"
+	inputs[NPFieldNew].EchoMode = textinput.EchoPassword
+	inputs[NPFieldNew].Focus()
+
+	inputs[NPFieldConfirm] = textinput.New()
+	inputs[NPFieldConfirm].Placeholder = "Confirm New Password"
+	inputs[NPFieldConfirm].EchoMode = textinput.EchoPassword
+
+	return NewPassModel{
+		Inputs:  inputs,
+		Focused: NPFieldNew,
+		Title:   title,
+		Hint:    hint,
+	}
+}
+
+// Values returns the new password and its confirmation.
+func (m NewPassModel) Values() (string, string) {
+	return m.Inputs[NPFieldNew].Value(), m.Inputs[NPFieldConfirm].Value()
+}
+
+func (m NewPassModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m NewPassModel) Update(msg tea.Msg) (NewPassModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "tab", "shift+tab", "enter", "up", "down":
+			s := msg.String()
+
+			// Did user press enter on last field?
+			if s == "enter" && m.Focused == NPFieldCount-1 {
+				return m, nil // Handled by parent
+			}
+
+			// Cycle focus
+			if s == "up" || s == "shift+tab" {
+				m.Focused--
+			} else {
+				m.Focused++
+			}
+
+			if m.Focused > NPFieldCount-1 {
+				m.Focused = 0
+			} else if m.Focused < 0 {
+				m.Focused = NPFieldCount - 1
+			}
+
+			cmds := make([]tea.Cmd, len(m.Inputs))
+			for i := 0; i <= int(NPFieldCount-1); i++ {
+				if i == int(m.Focused) {
+					cmds[i] = m.Inputs[i].Focus()
+					continue
+				}
+				m.Inputs[i].Blur()
+			}
+			return m, tea.Batch(cmds...)
+		}
+	}
+
+	cmds := make([]tea.Cmd, len(m.Inputs))
+	for i := range m.Inputs {
+		m.Inputs[i], cmds[i] = m.Inputs[i].Update(msg)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+func (m NewPassModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(StyleListHeader.Render(m.Title))
+	b.WriteString("\n\n")
+
+	if m.Hint != "" {
+		b.WriteString(StyleSubtext.Render(m.Hint))
+		b.WriteString("\n\n")
+	}
+
+	for i, input := range m.Inputs {
+		label := ""
+		switch NPField(i) {
+		case NPFieldNew:
+			label = "New Pass"
+		case NPFieldConfirm:
+			label = "Retype New"
+		}
+
+		style := StyleEditorLabel.Copy().Width(14)
+		if NPField(i) == m.Focused {
+			style = style.Foreground(ColorPrimary)
+		}
+
+		b.WriteString(style.Render(label))
+		b.WriteString("\n")
+		b.WriteString(input.View())
+		b.WriteString("\n\n")
+	}
+
+	b.WriteString(StyleSubtext.Render(" [tab] next • [enter] save"))
+
+	return b.String()
+}
@@ -0,0 +1,221 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fezcode/atlas.compass/internal/store"
+)
+
+type SlotsMode int
+
+const (
+	SlotsBrowse SlotsMode = iota
+	SlotsAddPassword
+	SlotsAddKeyfile
+	SlotsConfirmRemove
+	SlotsShowRecovery
+)
+
+type SlotsModel struct {
+	Slots   []store.SlotInfo
+	Cursor  int
+	Mode    SlotsMode
+	Inputs  []textinput.Model
+	Focused int
+	Words   []string // Recovery key being shown once
+}
+
+func NewSlotsModel(slots []store.SlotInfo) SlotsModel {
+	return SlotsModel{Slots: slots}
+}
+
+// StartAddPassword switches to the form for a new password slot.
+func (m *SlotsModel) StartAddPassword() tea.Cmd {
+	newPass := textinput.New()
+	newPass.Placeholder = "New Password"
+	newPass.EchoMode = textinput.EchoPassword
+
+	confirm := textinput.New()
+	confirm.Placeholder = "Confirm Password"
+	confirm.EchoMode = textinput.EchoPassword
+
+	m.Mode = SlotsAddPassword
+	m.Inputs = []textinput.Model{newPass, confirm}
+	m.Focused = 0
+	return m.Inputs[0].Focus()
+}
+
+// StartAddKeyfile switches to the form for a new keyfile slot.
+func (m *SlotsModel) StartAddKeyfile() tea.Cmd {
+	path := textinput.New()
+	path.Placeholder = "/media/usb/compass.key"
+	path.Width = 40
+
+	m.Mode = SlotsAddKeyfile
+	m.Inputs = []textinput.Model{path}
+	m.Focused = 0
+	return m.Inputs[0].Focus()
+}
+
+// ShowRecovery displays a freshly generated recovery key.
+func (m *SlotsModel) ShowRecovery(words []string) {
+	m.Browse()
+	m.Mode = SlotsShowRecovery
+	m.Words = words
+}
+
+// Browse returns to the slot list, dropping any form input.
+func (m *SlotsModel) Browse() {
+	m.Mode = SlotsBrowse
+	m.Inputs = nil
+	m.Focused = 0
+	m.Words = nil
+}
+
+// OnLastField reports whether the focused input is the last one of the form.
+func (m SlotsModel) OnLastField() bool {
+	return m.Focused == len(m.Inputs)-1
+}
+
+// Selected returns the slot under the cursor.
+func (m SlotsModel) Selected() (store.SlotInfo, bool) {
+	if m.Cursor < 0 || m.Cursor >= len(m.Slots) {
+		return store.SlotInfo{}, false
+	}
+	return m.Slots[m.Cursor], true
+}
+
+func (m SlotsModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m SlotsModel) Update(msg tea.Msg) (SlotsModel, tea.Cmd) {
+	keyMsg, isKey := msg.(tea.KeyMsg)
+
+	if m.Mode == SlotsBrowse {
+		if isKey {
+			switch keyMsg.String() {
+			case "up", "k":
+				if m.Cursor > 0 {
+					m.Cursor--
+				}
+			case "down", "j":
+				if m.Cursor < len(m.Slots)-1 {
+					m.Cursor++
+				}
+			}
+		}
+		return m, nil
+	}
+
+	if len(m.Inputs) == 0 {
+		return m, nil
+	}
+
+	if isKey {
+		switch keyMsg.String() {
+		case "tab", "shift+tab", "enter", "up", "down":
+			s := keyMsg.String()
+
+			// Did user press enter on last field?
+			if s == "enter" && m.OnLastField() {
+				return m, nil // Handled by parent
+			}
+
+			if s == "up" || s == "shift+tab" {
+				m.Focused--
+			} else {
+				m.Focused++
+			}
+			if m.Focused >= len(m.Inputs) {
+				m.Focused = 0
+			} else if m.Focused < 0 {
+				m.Focused = len(m.Inputs) - 1
+			}
+
+			cmds := make([]tea.Cmd, len(m.Inputs))
+			for i := range m.Inputs {
+				if i == m.Focused {
+					cmds[i] = m.Inputs[i].Focus()
+					continue
+				}
+				m.Inputs[i].Blur()
+			}
+			return m, tea.Batch(cmds...)
+		}
+	}
+
+	cmds := make([]tea.Cmd, len(m.Inputs))
+	for i := range m.Inputs {
+		m.Inputs[i], cmds[i] = m.Inputs[i].Update(msg)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+func (m SlotsModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(StyleListHeader.Render("Key Slots"))
+	b.WriteString("\n\n")
+
+	b.WriteString(StyleSubtext.Render("Each slot is an independent secret that unlocks this vault."))
+	b.WriteString("\n\n")
+
+	for i, s := range m.Slots {
+		line := fmt.Sprintf("#%d  %-16s %s", s.Index, s.Kind, s.KDF)
+		if i == m.Cursor {
+			b.WriteString(StyleListItemSelected.Render() + StyleBase.Foreground(ColorPrimary).Render(line))
+		} else {
+			b.WriteString(StyleListItem.Render(line))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
+	switch m.Mode {
+	case SlotsBrowse:
+		b.WriteString(StyleSubtext.Render(" [a] add password • [f] add keyfile • [r] add recovery key • [d] remove • [esc] back"))
+	case SlotsShowRecovery:
+		b.WriteString(StyleAuthHeader.Render("YOUR RECOVERY KEY"))
+		b.WriteString("\n")
+		for i, w := range m.Words {
+			b.WriteString(StyleBase.Render(fmt.Sprintf("%2d. %-8s", i+1, w)))
+			if (i+1)%6 == 0 {
+				b.WriteString("\n")
+			}
+		}
+		b.WriteString("\n")
+		b.WriteString(StyleSubtext.Render("Write these words down and store them offline. They are shown ONLY ONCE."))
+		b.WriteString("\n")
+		b.WriteString(StyleSubtext.Render("Use them on the unlock screen ([tab] to Recovery Key) if you lose your password."))
+		b.WriteString("\n\n")
+		b.WriteString(StyleSubtext.Render(" [enter] I have written it down"))
+	case SlotsAddPassword, SlotsAddKeyfile:
+		labels := []string{"New Pass", "Retype New"}
+		if m.Mode == SlotsAddKeyfile {
+			labels = []string{"Keyfile Path"}
+		}
+		for i, input := range m.Inputs {
+			style := StyleEditorLabel.Copy().Width(14)
+			if i == m.Focused {
+				style = style.Foreground(ColorPrimary)
+			}
+			b.WriteString(style.Render(labels[i]))
+			b.WriteString("\n")
+			b.WriteString(input.View())
+			b.WriteString("\n\n")
+		}
+		b.WriteString(StyleSubtext.Render(" [tab] next • [enter] add slot • [esc] cancel"))
+	case SlotsConfirmRemove:
+		if s, ok := m.Selected(); ok {
+			b.WriteString(StyleBase.Render(fmt.Sprintf("Remove slot #%d (%s)? It will no longer unlock this vault.", s.Index, s.Kind)))
+			b.WriteString("\n\n")
+		}
+		b.WriteString(StyleSubtext.Render(" [y] Yes, Remove • [n] No, Cancel"))
+	}
+
+	return b.String()
+}
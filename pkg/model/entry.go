@@ -1,21 +1,45 @@
 package model
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 )
 
-// Entry represents a single password entry in the vault.
+// Entry represents a single password entry in the vault. Its JSON form is
+// also the output schema of the CLI, so every field is always present.
 type Entry struct {
 	ID        string    `json:"id"`
 	Title     string    `json:"title"`
 	Username  string    `json:"username"`
 	Password  string    `json:"password"`
-	URL       string    `json:"url,omitempty"`
-	Notes     string    `json:"notes,omitempty"`
+	URL       string    `json:"url"`
+	Notes     string    `json:"notes"`
+	Tags      []string  `json:"tags"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// MarshalJSON writes untagged entries with an empty tag list rather than
+// null, keeping the schema the same for every entry.
+func (e Entry) MarshalJSON() ([]byte, error) {
+	type plain Entry // Without this method
+	if e.Tags == nil {
+		e.Tags = []string{}
+	}
+	return json.Marshal(plain(e))
+}
+
+// HasTag reports whether e carries tag, ignoring case.
+func (e Entry) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
 // Vault represents the decrypted content of the password store.
 type Vault struct {
 	Entries []Entry `json:"entries"`
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrEntryNotFound is returned when no entry matches a reference.
+	ErrEntryNotFound = errors.New("entry not found")
+
+	// ErrAmbiguous is returned when a title matches more than one entry.
+	ErrAmbiguous = errors.New("ambiguous entry")
+)
+
+// NewID returns a random entry ID.
+func NewID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Find returns the index of the entry whose ID is ref or, failing that,
+// whose title equals ref ignoring case.
+func (v *Vault) Find(ref string) (int, error) {
+	for i, e := range v.Entries {
+		if e.ID == ref {
+			return i, nil
+		}
+	}
+
+	var matches []int
+	for i, e := range v.Entries {
+		if strings.EqualFold(e.Title, ref) {
+			matches = append(matches, i)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return -1, fmt.Errorf("%w: %q", ErrEntryNotFound, ref)
+	case 1:
+		return matches[0], nil
+	}
+
+	ids := make([]string, len(matches))
+	for i, idx := range matches {
+		ids[i] = v.Entries[idx].ID
+	}
+	return -1, fmt.Errorf("%w: %q matches %d entries (%s); use an ID instead", ErrAmbiguous, ref, len(matches), strings.Join(ids, ", "))
+}
+
+// Remove deletes the entry at index i.
+func (v *Vault) Remove(i int) {
+	v.Entries = append(v.Entries[:i], v.Entries[i+1:]...)
+}
@@ -0,0 +1,182 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// Conflict is an entry changed differently on both sides of a merge. A nil
+// side means that side deleted it.
+type Conflict struct {
+	ID     string
+	Ours   *Entry
+	Theirs *Entry
+}
+
+// Newer returns the side updated last, preferring a change over a deletion.
+func (c Conflict) Newer() *Entry {
+	switch {
+	case c.Ours == nil:
+		return c.Theirs
+	case c.Theirs == nil:
+		return c.Ours
+	case c.Theirs.UpdatedAt.After(c.Ours.UpdatedAt):
+		return c.Theirs
+	}
+	return c.Ours
+}
+
+// Choice says how to resolve a Conflict.
+type Choice int
+
+const (
+	KeepOurs Choice = iota
+	KeepTheirs
+	KeepBoth // Keeps their version as a copy with a new ID
+)
+
+// Equal reports whether e and o are the same version of an entry.
+func (e Entry) Equal(o Entry) bool {
+	return e.ID == o.ID && e.Title == o.Title && e.Username == o.Username &&
+		e.Password == o.Password && e.URL == o.URL && e.Notes == o.Notes &&
+		slices.Equal(e.Tags, o.Tags) &&
+		e.CreatedAt.Equal(o.CreatedAt) && e.UpdatedAt.Equal(o.UpdatedAt)
+}
+
+// sameContent reports whether e and o differ at most in UpdatedAt.
+func (e Entry) sameContent(o Entry) bool {
+	o.UpdatedAt = e.UpdatedAt
+	return e.Equal(o)
+}
+
+// Clone returns a copy of v that shares no memory with it.
+func (v *Vault) Clone() *Vault {
+	c := &Vault{Entries: make([]Entry, len(v.Entries))}
+	for i, e := range v.Entries {
+		e.Tags = slices.Clone(e.Tags)
+		c.Entries[i] = e
+	}
+	return c
+}
+
+// Merge combines ours and theirs, two vaults changed independently since
+// base, entry by entry: a side that left an entry as it was in base takes
+// the other side's version, including a deletion. If both sides made the
+// same change, or differ only in UpdatedAt, the later one is kept.
+//
+// Anything else is a conflict. The merged vault then holds our version of
+// the entry (or none, if we deleted it) until the conflict is resolved with
+// Resolve. Entries keep our order; those only theirs has are appended.
+func Merge(base, ours, theirs *Vault) (*Vault, []Conflict) {
+	baseByID := index(base)
+	theirsByID := index(theirs)
+	oursByID := index(ours)
+
+	merged := &Vault{Entries: []Entry{}}
+	var conflicts []Conflict
+	take := func(id string) {
+		b, inBase := baseByID[id]
+		o, inOurs := oursByID[id]
+		t, inTheirs := theirsByID[id]
+		switch {
+		case inOurs && inTheirs && o.sameContent(t):
+			merged.Entries = append(merged.Entries, later(o, t))
+		case !inOurs && !inTheirs:
+			// Deleted on both sides
+		case unchanged(b, inBase, o, inOurs):
+			if inTheirs {
+				merged.Entries = append(merged.Entries, t)
+			}
+		case unchanged(b, inBase, t, inTheirs):
+			if inOurs {
+				merged.Entries = append(merged.Entries, o)
+			}
+		default:
+			c := Conflict{ID: id}
+			if inOurs {
+				c.Ours = &o
+				merged.Entries = append(merged.Entries, o)
+			}
+			if inTheirs {
+				c.Theirs = &t
+			}
+			conflicts = append(conflicts, c)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, e := range ours.Entries {
+		if !seen[e.ID] {
+			seen[e.ID] = true
+			take(e.ID)
+		}
+	}
+	// Entries we deleted come next, so their conflicts are found too
+	for _, e := range base.Entries {
+		if !seen[e.ID] {
+			seen[e.ID] = true
+			take(e.ID)
+		}
+	}
+	for _, e := range theirs.Entries {
+		if !seen[e.ID] {
+			seen[e.ID] = true
+			take(e.ID)
+		}
+	}
+	return merged, conflicts
+}
+
+// Resolve applies choice to a conflict returned by Merge for v.
+func (v *Vault) Resolve(c Conflict, choice Choice) {
+	i := slices.IndexFunc(v.Entries, func(e Entry) bool { return e.ID == c.ID })
+	switch choice {
+	case KeepOurs:
+		// Merge already holds our version
+	case KeepTheirs:
+		switch {
+		case c.Theirs == nil && i >= 0:
+			v.Remove(i)
+		case c.Theirs != nil && i >= 0:
+			v.Entries[i] = *c.Theirs
+		case c.Theirs != nil:
+			v.Entries = append(v.Entries, *c.Theirs)
+		}
+	case KeepBoth:
+		if c.Theirs == nil {
+			return
+		}
+		if i < 0 {
+			v.Entries = append(v.Entries, *c.Theirs)
+			return
+		}
+		dup := *c.Theirs
+		dup.ID = NewID()
+		dup.Title += " (conflicting copy)"
+		dup.UpdatedAt = time.Now()
+		v.Entries = append(v.Entries, dup)
+	}
+}
+
+func index(v *Vault) map[string]Entry {
+	m := make(map[string]Entry, len(v.Entries))
+	for _, e := range v.Entries {
+		m[e.ID] = e
+	}
+	return m
+}
+
+// unchanged reports whether e (present if ok) is still as it was in base.
+func unchanged(base Entry, inBase bool, e Entry, ok bool) bool {
+	if inBase != ok {
+		return false
+	}
+	return !ok || base.Equal(e)
+}
+
+func later(a, b Entry) Entry {
+	if b.UpdatedAt.After(a.UpdatedAt) {
+		return b
+	}
+	return a
+}
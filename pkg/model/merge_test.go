@@ -0,0 +1,213 @@
+package model
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// entry returns an entry updated at minute min after t0.
+func entry(id, notes string, min int) Entry {
+	return Entry{ID: id, Title: strings.ToUpper(id), Notes: notes, CreatedAt: t0, UpdatedAt: t0.Add(time.Duration(min) * time.Minute)}
+}
+
+func vault(entries ...Entry) *Vault {
+	return &Vault{Entries: entries}
+}
+
+// summary lists the entries of v as "id:notes".
+func summary(v *Vault) []string {
+	s := make([]string, len(v.Entries))
+	for i, e := range v.Entries {
+		s[i] = e.ID + ":" + e.Notes
+	}
+	return s
+}
+
+func TestMerge(t *testing.T) {
+	a, b := entry("a", "base", 0), entry("b", "base", 0)
+
+	tests := []struct {
+		name               string
+		base, ours, theirs *Vault
+		want               []string
+		conflicts          []string // IDs
+	}{
+		{
+			name: "nothing changed",
+			base: vault(a, b), ours: vault(a, b), theirs: vault(a, b),
+			want: []string{"a:base", "b:base"},
+		},
+		{
+			name: "changed on our side only",
+			base: vault(a, b), ours: vault(entry("a", "ours", 1), b), theirs: vault(a, b),
+			want: []string{"a:ours", "b:base"},
+		},
+		{
+			name: "changed on their side only",
+			base: vault(a, b), ours: vault(a, b), theirs: vault(a, entry("b", "theirs", 1)),
+			want: []string{"a:base", "b:theirs"},
+		},
+		{
+			name: "each side changed another entry",
+			base: vault(a, b), ours: vault(entry("a", "ours", 1), b), theirs: vault(a, entry("b", "theirs", 1)),
+			want: []string{"a:ours", "b:theirs"},
+		},
+		{
+			name: "same change on both sides",
+			base: vault(a), ours: vault(entry("a", "same", 1)), theirs: vault(entry("a", "same", 2)),
+			want: []string{"a:same"},
+		},
+		{
+			name: "different changes to the same field",
+			base: vault(a, b), ours: vault(entry("a", "ours", 1), b), theirs: vault(entry("a", "theirs", 2), b),
+			want:      []string{"a:ours", "b:base"},
+			conflicts: []string{"a"},
+		},
+		{
+			name: "deleted on their side only",
+			base: vault(a, b), ours: vault(a, b), theirs: vault(b),
+			want: []string{"b:base"},
+		},
+		{
+			name: "deleted here, edited there",
+			base: vault(a, b), ours: vault(b), theirs: vault(entry("a", "theirs", 1), b),
+			want:      []string{"b:base"},
+			conflicts: []string{"a"},
+		},
+		{
+			name: "edited here, deleted there",
+			base: vault(a, b), ours: vault(entry("a", "ours", 1), b), theirs: vault(b),
+			want:      []string{"a:ours", "b:base"},
+			conflicts: []string{"a"},
+		},
+		{
+			name: "deleted on both sides",
+			base: vault(a, b), ours: vault(b), theirs: vault(b),
+			want: []string{"b:base"},
+		},
+		{
+			name: "added on both sides",
+			base: vault(a), ours: vault(a, entry("c", "ours", 1)), theirs: vault(a, entry("d", "theirs", 1)),
+			want: []string{"a:base", "c:ours", "d:theirs"},
+		},
+		{
+			name: "same ID added differently on both sides",
+			base: vault(), ours: vault(entry("c", "ours", 1)), theirs: vault(entry("c", "theirs", 2)),
+			want:      []string{"c:ours"},
+			conflicts: []string{"c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, conflicts := Merge(tt.base, tt.ours, tt.theirs)
+			if got := summary(merged); !slices.Equal(got, tt.want) {
+				t.Errorf("merged = %v, want %v", got, tt.want)
+			}
+			var ids []string
+			for _, c := range conflicts {
+				ids = append(ids, c.ID)
+			}
+			if !slices.Equal(ids, tt.conflicts) {
+				t.Errorf("conflicts = %v, want %v", ids, tt.conflicts)
+			}
+		})
+	}
+}
+
+func TestMergeKeepsLaterTimestamp(t *testing.T) {
+	merged, _ := Merge(vault(entry("a", "base", 0)), vault(entry("a", "same", 1)), vault(entry("a", "same", 2)))
+	if got, want := merged.Entries[0].UpdatedAt, t0.Add(2*time.Minute); !got.Equal(want) {
+		t.Errorf("UpdatedAt = %v, want %v", got, want)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	base := vault(entry("a", "base", 0), entry("b", "base", 0))
+
+	tests := []struct {
+		name         string
+		ours, theirs *Vault
+		choice       Choice
+		want         []string
+	}{
+		{"edit vs edit, keep ours", vault(entry("a", "ours", 1), entry("b", "base", 0)), vault(entry("a", "theirs", 2), entry("b", "base", 0)), KeepOurs, []string{"a:ours", "b:base"}},
+		{"edit vs edit, keep theirs", vault(entry("a", "ours", 1), entry("b", "base", 0)), vault(entry("a", "theirs", 2), entry("b", "base", 0)), KeepTheirs, []string{"a:theirs", "b:base"}},
+		{"edit vs edit, keep both", vault(entry("a", "ours", 1), entry("b", "base", 0)), vault(entry("a", "theirs", 2), entry("b", "base", 0)), KeepBoth, []string{"a:ours", "b:base", "?:theirs"}},
+		{"our delete, keep ours", vault(entry("b", "base", 0)), vault(entry("a", "theirs", 1), entry("b", "base", 0)), KeepOurs, []string{"b:base"}},
+		{"our delete, keep theirs", vault(entry("b", "base", 0)), vault(entry("a", "theirs", 1), entry("b", "base", 0)), KeepTheirs, []string{"b:base", "a:theirs"}},
+		{"our delete, keep both", vault(entry("b", "base", 0)), vault(entry("a", "theirs", 1), entry("b", "base", 0)), KeepBoth, []string{"b:base", "a:theirs"}},
+		{"their delete, keep theirs", vault(entry("a", "ours", 1), entry("b", "base", 0)), vault(entry("b", "base", 0)), KeepTheirs, []string{"b:base"}},
+		{"their delete, keep both", vault(entry("a", "ours", 1), entry("b", "base", 0)), vault(entry("b", "base", 0)), KeepBoth, []string{"a:ours", "b:base"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, conflicts := Merge(base, tt.ours, tt.theirs)
+			if len(conflicts) != 1 {
+				t.Fatalf("got %d conflicts, want 1", len(conflicts))
+			}
+			merged.Resolve(conflicts[0], tt.choice)
+
+			got := summary(merged)
+			for i, e := range merged.Entries {
+				if e.ID != "a" && e.ID != "b" {
+					got[i] = "?:" + e.Notes // A fresh ID, see TestResolveKeepBothCopy
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("resolved = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveKeepBothCopy(t *testing.T) {
+	ours := vault(entry("a", "ours", 1))
+	theirs := vault(entry("a", "theirs", 2))
+	merged, conflicts := Merge(vault(entry("a", "base", 0)), ours, theirs)
+	merged.Resolve(conflicts[0], KeepBoth)
+
+	if len(merged.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(merged.Entries))
+	}
+	kept, dup := merged.Entries[0], merged.Entries[1]
+	if !kept.Equal(ours.Entries[0]) {
+		t.Errorf("our entry changed: %+v", kept)
+	}
+	if dup.ID == "" || dup.ID == "a" {
+		t.Errorf("copy has ID %q, want a new one", dup.ID)
+	}
+	if want := "A (conflicting copy)"; dup.Title != want {
+		t.Errorf("copy title = %q, want %q", dup.Title, want)
+	}
+	if dup.Notes != "theirs" || !dup.CreatedAt.Equal(t0) {
+		t.Errorf("copy = %+v, want their content", dup)
+	}
+	if theirs.Entries[0].ID != "a" {
+		t.Error("Resolve changed the conflict's entry")
+	}
+
+	// Resolving another conflict the same way gives another ID
+	merged2, conflicts2 := Merge(vault(entry("a", "base", 0)), ours, theirs)
+	merged2.Resolve(conflicts2[0], KeepBoth)
+	if merged2.Entries[1].ID == dup.ID {
+		t.Errorf("two copies share ID %q", dup.ID)
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	e := entry("a", "base", 0)
+	e.Tags = []string{"x"}
+	v := vault(e)
+	c := v.Clone()
+	c.Entries[0].Tags[0] = "y"
+	c.Entries[0].Notes = "changed"
+	if v.Entries[0].Tags[0] != "x" || v.Entries[0].Notes != "base" {
+		t.Errorf("Clone shares memory: %+v", v.Entries[0])
+	}
+}